@@ -0,0 +1,222 @@
+package deepgorm
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// correlatedAlias is the alias of the related table in correlated subqueries, since the related table might
+// be the same as the table of the record itself.
+const correlatedAlias = "deepgorm_related"
+
+// numericDataTypes are the column types that can be summed or averaged
+var numericDataTypes = []schema.DataType{schema.Int, schema.Uint, schema.Float}
+
+// The types that the values compared with counts and with sums and averages are converted to, refer to coercerOfType
+var (
+	countType   = reflect.TypeOf(int64(0))
+	numericType = reflect.TypeOf(float64(0))
+)
+
+// aggregate describes an aggregate function that can be applied to a column of related records
+type aggregate struct {
+	// function is the SQL function
+	function string
+
+	// dataTypes limits the column types the aggregate may be used on
+	dataTypes []schema.DataType
+}
+
+// aggregates godoc
+// All the aggregates that may be used on to-many relations, for example:
+//
+//	map[string]any{
+//		"line_items": map[string]any{
+//			"$sum": map[string]any{
+//				"amount": map[string]any{"$gt": 1000},
+//			},
+//		},
+//	}
+var aggregates = map[string]aggregate{
+	"$sum": {function: "SUM", dataTypes: numericDataTypes},
+	"$avg": {function: "AVG", dataTypes: numericDataTypes},
+	"$min": {function: "MIN", dataTypes: orderedDataTypes},
+	"$max": {function: "MAX", dataTypes: orderedDataTypes},
+}
+
+// correlatedSubquery creates a subquery that applies the function to the column of all the records related to the
+// record in the outer query, for example:
+//
+//	(SELECT SUM(deepgorm_related.amount) FROM line_items deepgorm_related WHERE deepgorm_related.invoice_id = invoices.id)
+//
+// Only works with manyToOne and manyToMany relations.
+func correlatedSubquery(schemaInfo *schema.Schema, fieldInfo *nestedType, function string, column any) (clause.Expr, error) {
+	sql := fmt.Sprintf("(SELECT %s(?) FROM ? WHERE ?)", function)
+	relatedTable := clause.Table{Name: fieldInfo.fieldTable, Alias: correlatedAlias}
+	referenceKeys := columnsOf(schemaInfo.Table, fieldInfo.referenceKeys)
+
+	switch fieldInfo.relationType {
+	case "manyToOne":
+		// (SELECT <function>(<column>) FROM <related> WHERE <related>.<fk> = <table>.<reference>)
+		correlation := columnsEqual(columnsOf(correlatedAlias, fieldInfo.fieldForeignKeys), referenceKeys)
+
+		// AND <related>.<type> = <value> for polymorphic relations
+		if fieldInfo.polymorphicType != "" {
+			correlation = clause.And(correlation, polymorphicCondition(correlatedAlias, fieldInfo))
+		}
+
+		return clause.Expr{SQL: sql, Vars: []any{column, relatedTable, correlation}}, nil
+
+	case "manyToMany":
+		// (SELECT <function>(<column>) FROM <related> WHERE EXISTS (SELECT 1 FROM <join table>
+		//   WHERE <join table>.<fk> = <related>.<reference> AND <join table>.<destination fk> = <table>.<reference>))
+		correlation := clause.And(
+			columnsEqual(columnsOf(fieldInfo.manyToManyTable, fieldInfo.fieldForeignKeys), columnsOf(correlatedAlias, fieldInfo.fieldReferenceKeys)),
+			columnsEqual(columnsOf(fieldInfo.manyToManyTable, fieldInfo.destinationManyToManyForeignKeys), referenceKeys),
+		)
+		joined := clause.Expr{SQL: "EXISTS (SELECT 1 FROM ? WHERE ?)", Vars: []any{clause.Table{Name: fieldInfo.manyToManyTable}, correlation}}
+
+		return clause.Expr{SQL: sql, Vars: []any{column, relatedTable, joined}}, nil
+	}
+
+	return clause.Expr{}, fmt.Errorf("relationType '%s' unsupported in correlated subqueries", fieldInfo.relationType)
+}
+
+// compareValue compares the column with a single value, or with a map of operators. The values are converted using
+// coerce if it's not nil, refer to coercerOf.
+func compareValue(column any, dataType schema.DataType, coerce coercer, name string, value any) (clause.Expression, error) {
+	filter, isMap := value.(map[string]any)
+
+	switch {
+	// A single value means equality
+	case !isMap:
+		value, err := coerceOperatorValue(coerce, name, value)
+		if err != nil {
+			return nil, err
+		}
+
+		return clause.Eq{Column: column, Value: value}, nil
+
+	case !isOperatorMap(filter):
+		return nil, fmt.Errorf("'%s' expects a value or a map of operators: %w", name, ErrInvalidOperatorValue)
+	}
+
+	return compare(column, dataType, coerce, filter)
+}
+
+// buildCountExpression godoc
+// Compares the number of related records, either with a number or a map of operators:
+//
+//	map[string]any{
+//		"object_bs": map[string]any{
+//			"$count": map[string]any{"$gte": 3},
+//		},
+//	}
+//
+// Is turned into:
+//
+//	(SELECT COUNT(*) FROM object_bs deepgorm_related WHERE deepgorm_related.object_a_id = object_as.id) >= 3
+func buildCountExpression(schemaInfo *schema.Schema, fieldName string, fieldInfo *nestedType, value any) (clause.Expression, error) {
+	count, err := correlatedSubquery(schemaInfo, fieldInfo, "COUNT", clause.Expr{SQL: "*"})
+	if err != nil {
+		return nil, err
+	}
+
+	expression, err := compareValue(count, schema.Int, coercerOfType(countType), "$count", value)
+	if err != nil {
+		return nil, newRelationOperatorError(schemaInfo, fieldName, "$count", value, err)
+	}
+
+	return expression, nil
+}
+
+// buildAggregateExpression godoc
+// Compares the result of an aggregate function over a column of the related records, refer to aggregates. Multiple
+// columns are combined using AND. If there are no related records, the result is NULL and nothing matches.
+func (d *DeepGorm) buildAggregateExpression(db *gorm.DB, schemaInfo *schema.Schema, fieldName string, fieldInfo *nestedType, name string, value any) (clause.Expression, error) {
+	filter, ok := value.(map[string]any)
+	if !ok || len(filter) == 0 {
+		return nil, newRelationOperatorError(schemaInfo, fieldName, name, value, fmt.Errorf("'%s' expects a non-empty map of columns: %w", name, ErrInvalidOperatorValue))
+	}
+
+	relatedSchema, err := d.parseSchema(db, fieldInfo.fieldStructInstance)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sort the columns to get a predictable query
+	keys := sortedKeys(filter)
+
+	columns := d.getKeysOfType(db.NamingStrategy, relatedSchema)
+	expressions := make([]clause.Expression, 0, len(keys))
+
+	var errs []error
+
+	for _, key := range keys {
+		// Keys like {"unitPrice": ...} refer to a column, refer to WithKeys
+		column, ok := columns[key]
+		if !ok {
+			errs = append(errs, prependPath(newFilterError(relatedSchema, key, filter[key], ErrFieldDoesNotExist), fieldName, name))
+			continue
+		}
+
+		expression, err := buildAggregateColumnExpression(schemaInfo, relatedSchema, fieldInfo, name, column, filter[key])
+		if err != nil {
+			errs = append(errs, prependPath(renamePath(err, column, key), fieldName, name))
+			continue
+		}
+
+		expressions = append(expressions, expression)
+	}
+
+	if len(errs) > 0 {
+		return nil, joinErrors(errs)
+	}
+
+	return clause.And(expressions...), nil
+}
+
+// buildAggregateColumnExpression compares the result of the aggregate over a single column of the related records
+// with the value, refer to buildAggregateExpression
+func buildAggregateColumnExpression(schemaInfo *schema.Schema, relatedSchema *schema.Schema, fieldInfo *nestedType, name string, column string, value any) (clause.Expression, error) {
+	aggregateInfo := aggregates[name]
+
+	field, ok := relatedSchema.FieldsByDBName[column]
+	if !ok {
+		return nil, newFilterError(relatedSchema, column, value, ErrFieldDoesNotExist)
+	}
+
+	if !slices.Contains(aggregateInfo.dataTypes, field.DataType) {
+		err := fmt.Errorf("'%s' can't be used on type '%s': %w", name, field.DataType, ErrUnsupportedOperator)
+		return nil, newFilterError(relatedSchema, column, value, err)
+	}
+
+	result, err := correlatedSubquery(schemaInfo, fieldInfo, aggregateInfo.function, clause.Column{Table: correlatedAlias, Name: column})
+	if err != nil {
+		return nil, err
+	}
+
+	// The average of integers isn't an integer
+	dataType := field.DataType
+	if name == "$avg" {
+		dataType = schema.Float
+	}
+
+	// The minimum and maximum have the type of the column, sums and averages are compared as numbers
+	coerce := coercerOfType(numericType)
+	if name == "$min" || name == "$max" {
+		coerce = coercerOf(field)
+	}
+
+	expression, err := compareValue(result, dataType, coerce, name, value)
+	if err != nil {
+		return nil, newFilterError(relatedSchema, column, value, err)
+	}
+
+	return expression, nil
+}
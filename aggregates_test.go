@@ -0,0 +1,618 @@
+package deepgorm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ing-bank/gormtestutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type Invoice struct {
+	ID        uuid.UUID
+	Name      string
+	LineItems []*LineItem `gorm:"foreignKey:InvoiceID"`
+}
+
+type LineItem struct {
+	ID          uuid.UUID
+	InvoiceID   uuid.UUID
+	Description string
+	Amount      float64
+	Quantity    int
+	DueDate     time.Time
+}
+
+func TestAddDeepFilters_AddsCountFiltersWithManyToOne(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	records := []*ComplexStruct2{
+		{
+			ID:   uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+			Name: "three",
+			Tags: []*SimpleTag{
+				{ID: uuid.MustParse("1c83a7c9-e95d-4dba-b858-5eb4e34ebcf2"), Key: "a"},
+				{ID: uuid.MustParse("17983ba8-2d26-4e36-bb6b-6c5a04b6606e"), Key: "b"},
+				{ID: uuid.MustParse("451d635a-83f2-47da-b12c-50ec49e45509"), Key: "c"},
+			},
+		},
+		{
+			ID:   uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+			Name: "one",
+			Tags: []*SimpleTag{
+				{ID: uuid.MustParse("8977cd8b-ebb8-4119-93d5-cbe605d8f668"), Key: "a"},
+			},
+		},
+		{
+			ID:   uuid.MustParse("c98dc9f2-bfa5-4ab5-9cbb-76800e09e512"),
+			Name: "zero",
+		},
+	}
+
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedNames []string
+	}{
+		"exists": {
+			filterMap: map[string]any{
+				"tags": map[string]any{"$exists": true},
+			},
+			expectedNames: []string{"one", "three"},
+		},
+		"does not exist": {
+			filterMap: map[string]any{
+				"tags": map[string]any{"$exists": false},
+			},
+			expectedNames: []string{"zero"},
+		},
+		"count equals": {
+			filterMap: map[string]any{
+				"tags": map[string]any{"$count": 1},
+			},
+			expectedNames: []string{"one"},
+		},
+		"count equals zero": {
+			filterMap: map[string]any{
+				"tags": map[string]any{"$count": 0},
+			},
+			expectedNames: []string{"zero"},
+		},
+		"count greater than": {
+			filterMap: map[string]any{
+				"tags": map[string]any{"$count": map[string]any{"$gt": 1}},
+			},
+			expectedNames: []string{"three"},
+		},
+		"count less than": {
+			filterMap: map[string]any{
+				"tags": map[string]any{"$count": map[string]any{"$lt": 3}},
+			},
+			expectedNames: []string{"one", "zero"},
+		},
+		"count between": {
+			filterMap: map[string]any{
+				"tags": map[string]any{"$count": map[string]any{"$between": []int{1, 3}}},
+			},
+			expectedNames: []string{"one", "three"},
+		},
+		"count as string": {
+			filterMap: map[string]any{
+				"tags": map[string]any{"$count": "1"},
+			},
+			expectedNames: []string{"one"},
+		},
+		"count operator as string": {
+			filterMap: map[string]any{
+				"tags": map[string]any{"$count": map[string]any{"$gt": "1"}},
+			},
+			expectedNames: []string{"three"},
+		},
+		"count combined with filter": {
+			filterMap: map[string]any{
+				"tags": map[string]any{
+					"$count": map[string]any{"$gte": 1},
+					"key":    "b",
+				},
+			},
+			expectedNames: []string{"three"},
+		},
+		"not count": {
+			filterMap: map[string]any{
+				"$not": map[string]any{
+					"tags": map[string]any{"$count": map[string]any{"$gte": 1}},
+				},
+			},
+			expectedNames: []string{"zero"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&ComplexStruct2{}, &SimpleTag{})
+
+			database.CreateInBatches(records, len(records))
+
+			// Act
+			query, err := AddDeepFilters(database, ComplexStruct2{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []string
+				res := query.Model(&ComplexStruct2{}).Order("name").Pluck("name", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, testData.expectedNames, result)
+			}
+		})
+	}
+}
+
+func TestAddDeepFilters_AddsCountFiltersWithManyToMany(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	world := &ManyB{ID: uuid.MustParse("9f1baf72-6ca5-4d43-8a01-d845575620e1"), B: "world"}
+	moon := &ManyB{ID: uuid.MustParse("4604bb79-ee05-4a09-b874-c3af8964d8c4"), B: "moon"}
+
+	records := []*ManyA{
+		{
+			ID:     uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+			A:      "world only",
+			ManyBs: []*ManyB{world},
+		},
+		{
+			ID:     uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+			A:      "world and moon",
+			ManyBs: []*ManyB{world, moon},
+		},
+		{
+			ID: uuid.MustParse("c98dc9f2-bfa5-4ab5-9cbb-76800e09e512"),
+			A:  "empty",
+		},
+	}
+
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedNames []string
+	}{
+		"exists": {
+			filterMap: map[string]any{
+				"many_bs": map[string]any{"$exists": true},
+			},
+			expectedNames: []string{"world and moon", "world only"},
+		},
+		"does not exist": {
+			filterMap: map[string]any{
+				"many_bs": map[string]any{"$exists": false},
+			},
+			expectedNames: []string{"empty"},
+		},
+		"count": {
+			filterMap: map[string]any{
+				"many_bs": map[string]any{"$count": map[string]any{"$gte": 2}},
+			},
+			expectedNames: []string{"world and moon"},
+		},
+		"nested count": {
+			filterMap: map[string]any{
+				"many_bs": map[string]any{
+					"many_as": map[string]any{
+						"$count": 1,
+					},
+				},
+			},
+			expectedNames: []string{"world and moon"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&ManyA{}, &ManyB{})
+
+			database.CreateInBatches(records, len(records))
+
+			// Act
+			query, err := AddDeepFilters(database, ManyA{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []string
+				res := query.Model(&ManyA{}).Order("a").Pluck("a", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, testData.expectedNames, result)
+			}
+		})
+	}
+}
+
+func TestAddDeepFilters_ReturnsErrorOnInvalidCountFilters(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	tests := map[string]struct {
+		objectType    any
+		filterMap     map[string]any
+		expectedError error
+		expectedMsg   string
+	}{
+		"count on single relation": {
+			objectType: ComplexStruct1{},
+			filterMap: map[string]any{
+				"nested": map[string]any{"$count": 1},
+			},
+			expectedError: ErrUnsupportedOperator,
+			expectedMsg:   "failed to add filters for 'complex_struct1.nested': '$count' can only be used on to-many relations: unsupported operator",
+		},
+		"exists with a string": {
+			objectType: ComplexStruct2{},
+			filterMap: map[string]any{
+				"tags": map[string]any{"$exists": "yes"},
+			},
+			expectedError: ErrInvalidOperatorValue,
+			expectedMsg:   "failed to add filters for 'complex_struct2.tags': '$exists' expects a boolean: invalid operator value",
+		},
+		"count with a list": {
+			objectType: ComplexStruct2{},
+			filterMap: map[string]any{
+				"tags": map[string]any{"$count": []int{1, 2}},
+			},
+			expectedError: ErrInvalidOperatorValue,
+			expectedMsg:   "failed to add filters for 'complex_struct2.tags': '$count' expects a single value: invalid operator value",
+		},
+		"count with a filter": {
+			objectType: ComplexStruct2{},
+			filterMap: map[string]any{
+				"tags": map[string]any{"$count": map[string]any{"key": "a"}},
+			},
+			expectedError: ErrInvalidOperatorValue,
+			expectedMsg:   "failed to add filters for 'complex_struct2.tags': '$count' expects a value or a map of operators: invalid operator value",
+		},
+		"count with text": {
+			objectType: ComplexStruct2{},
+			filterMap: map[string]any{
+				"tags": map[string]any{"$count": "many"},
+			},
+			expectedError: ErrTypeMismatch,
+			expectedMsg:   "failed to add filters for 'complex_struct2.tags': can't convert 'many' (string) to int64: type mismatch",
+		},
+		"count with unsupported operator": {
+			objectType: ComplexStruct2{},
+			filterMap: map[string]any{
+				"tags": map[string]any{"$count": map[string]any{"$like": "1%"}},
+			},
+			expectedError: ErrUnsupportedOperator,
+			expectedMsg:   "failed to add filters for 'complex_struct2.tags': '$like' can't be used on type 'int': unsupported operator",
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+
+			// Act
+			query, err := AddDeepFilters(database, testData.objectType, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, query)
+			assert.ErrorIs(t, err, testData.expectedError)
+			assert.EqualError(t, err, testData.expectedMsg)
+		})
+	}
+}
+
+func TestAddDeepFilters_AddsAggregateFiltersWithManyToOne(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	past := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	future := time.Date(2040, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	records := []*Invoice{
+		{
+			ID:   uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+			Name: "large",
+			LineItems: []*LineItem{
+				{ID: uuid.MustParse("1c83a7c9-e95d-4dba-b858-5eb4e34ebcf2"), Amount: 800, Quantity: 1, DueDate: past},
+				{ID: uuid.MustParse("17983ba8-2d26-4e36-bb6b-6c5a04b6606e"), Amount: 400, Quantity: 2, DueDate: past},
+			},
+		},
+		{
+			ID:   uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+			Name: "small",
+			LineItems: []*LineItem{
+				{ID: uuid.MustParse("451d635a-83f2-47da-b12c-50ec49e45509"), Amount: 100, Quantity: 5, DueDate: past},
+				{ID: uuid.MustParse("8977cd8b-ebb8-4119-93d5-cbe605d8f668"), Amount: 200, Quantity: 1, DueDate: future},
+			},
+		},
+		{
+			ID:   uuid.MustParse("c98dc9f2-bfa5-4ab5-9cbb-76800e09e512"),
+			Name: "empty",
+		},
+	}
+
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedNames []string
+	}{
+		"sum": {
+			filterMap: map[string]any{
+				"line_items": map[string]any{
+					"$sum": map[string]any{"amount": map[string]any{"$gt": 1000}},
+				},
+			},
+			expectedNames: []string{"large"},
+		},
+		"sum equals": {
+			filterMap: map[string]any{
+				"line_items": map[string]any{
+					"$sum": map[string]any{"quantity": 6},
+				},
+			},
+			expectedNames: []string{"small"},
+		},
+		"avg": {
+			filterMap: map[string]any{
+				"line_items": map[string]any{
+					"$avg": map[string]any{"quantity": map[string]any{"$gte": 1.5, "$lt": 3}},
+				},
+			},
+			expectedNames: []string{"large"},
+		},
+		"sum as string": {
+			filterMap: map[string]any{
+				"line_items": map[string]any{
+					"$sum": map[string]any{"quantity": map[string]any{"$gt": "5"}},
+				},
+			},
+			expectedNames: []string{"small"},
+		},
+		"avg as string": {
+			filterMap: map[string]any{
+				"line_items": map[string]any{
+					"$avg": map[string]any{"amount": "600"},
+				},
+			},
+			expectedNames: []string{"large"},
+		},
+		"min": {
+			filterMap: map[string]any{
+				"line_items": map[string]any{
+					"$min": map[string]any{"amount": map[string]any{"$lte": 100}},
+				},
+			},
+			expectedNames: []string{"small"},
+		},
+		"latest due date in the past": {
+			filterMap: map[string]any{
+				"line_items": map[string]any{
+					"$max": map[string]any{"due_date": map[string]any{"$lt": now}},
+				},
+			},
+			expectedNames: []string{"large"},
+		},
+		"multiple columns": {
+			filterMap: map[string]any{
+				"line_items": map[string]any{
+					"$max": map[string]any{
+						"amount":   map[string]any{"$gte": 200},
+						"quantity": map[string]any{"$gte": 5},
+					},
+				},
+			},
+			expectedNames: []string{"small"},
+		},
+		"not sum": {
+			filterMap: map[string]any{
+				"$not": map[string]any{
+					"line_items": map[string]any{
+						"$sum": map[string]any{"amount": map[string]any{"$gt": 1000}},
+					},
+				},
+			},
+			expectedNames: []string{"small"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&Invoice{}, &LineItem{})
+
+			database.CreateInBatches(records, len(records))
+
+			// Act
+			query, err := AddDeepFilters(database, Invoice{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []string
+				res := query.Model(&Invoice{}).Order("name").Pluck("name", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, testData.expectedNames, result)
+			}
+		})
+	}
+}
+
+func TestAddDeepFilters_AddsAggregateFiltersWithManyToMany(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	world := &ManyB{ID: uuid.MustParse("9f1baf72-6ca5-4d43-8a01-d845575620e1"), B: "world"}
+	moon := &ManyB{ID: uuid.MustParse("4604bb79-ee05-4a09-b874-c3af8964d8c4"), B: "moon"}
+
+	records := []*ManyA{
+		{
+			ID:     uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+			A:      "world only",
+			ManyBs: []*ManyB{world},
+		},
+		{
+			ID:     uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+			A:      "world and moon",
+			ManyBs: []*ManyB{world, moon},
+		},
+		{
+			ID: uuid.MustParse("c98dc9f2-bfa5-4ab5-9cbb-76800e09e512"),
+			A:  "empty",
+		},
+	}
+
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedNames []string
+	}{
+		"min": {
+			filterMap: map[string]any{
+				"many_bs": map[string]any{
+					"$min": map[string]any{"b": "moon"},
+				},
+			},
+			expectedNames: []string{"world and moon"},
+		},
+		"max": {
+			filterMap: map[string]any{
+				"many_bs": map[string]any{
+					"$max": map[string]any{"b": map[string]any{"$gt": "n"}},
+				},
+			},
+			expectedNames: []string{"world and moon", "world only"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&ManyA{}, &ManyB{})
+
+			database.CreateInBatches(records, len(records))
+
+			// Act
+			query, err := AddDeepFilters(database, ManyA{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []string
+				res := query.Model(&ManyA{}).Order("a").Pluck("a", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, testData.expectedNames, result)
+			}
+		})
+	}
+}
+
+func TestAddDeepFilters_ReturnsErrorOnInvalidAggregateFilters(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedError error
+		expectedMsg   string
+	}{
+		"unknown column": {
+			filterMap: map[string]any{
+				"line_items": map[string]any{
+					"$sum": map[string]any{"price": map[string]any{"$gt": 5}},
+				},
+			},
+			expectedError: ErrFieldDoesNotExist,
+			expectedMsg:   "failed to add filters for 'line_items.price': field does not exist",
+		},
+		"sum on string": {
+			filterMap: map[string]any{
+				"line_items": map[string]any{
+					"$sum": map[string]any{"description": map[string]any{"$gt": 5}},
+				},
+			},
+			expectedError: ErrUnsupportedOperator,
+			expectedMsg:   "failed to add filters for 'line_items.description': '$sum' can't be used on type 'string': unsupported operator",
+		},
+		"avg with like": {
+			filterMap: map[string]any{
+				"line_items": map[string]any{
+					"$avg": map[string]any{"amount": map[string]any{"$like": "5%"}},
+				},
+			},
+			expectedError: ErrUnsupportedOperator,
+			expectedMsg:   "failed to add filters for 'line_items.amount': '$like' can't be used on type 'float': unsupported operator",
+		},
+		"sum with text": {
+			filterMap: map[string]any{
+				"line_items": map[string]any{
+					"$sum": map[string]any{"amount": map[string]any{"$gt": "lots"}},
+				},
+			},
+			expectedError: ErrTypeMismatch,
+			expectedMsg:   "failed to add filters for 'line_items.amount': can't convert 'lots' (string) to float64: type mismatch",
+		},
+		"no columns": {
+			filterMap: map[string]any{
+				"line_items": map[string]any{
+					"$max": map[string]any{},
+				},
+			},
+			expectedError: ErrInvalidOperatorValue,
+			expectedMsg:   "failed to add filters for 'invoices.line_items': '$max' expects a non-empty map of columns: invalid operator value",
+		},
+		"column with filter": {
+			filterMap: map[string]any{
+				"line_items": map[string]any{
+					"$min": map[string]any{"amount": map[string]any{"quantity": 5}},
+				},
+			},
+			expectedError: ErrInvalidOperatorValue,
+			expectedMsg:   "failed to add filters for 'line_items.amount': '$min' expects a value or a map of operators: invalid operator value",
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+
+			// Act
+			query, err := AddDeepFilters(database, Invoice{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, query)
+			assert.ErrorIs(t, err, testData.expectedError)
+			assert.EqualError(t, err, testData.expectedMsg)
+		})
+	}
+}
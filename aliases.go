@@ -0,0 +1,127 @@
+package deepgorm
+
+import (
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+// KeyResolver returns the key of a struct field in filters, or an empty string if it has none. The column is the key
+// that's used by default: the name of the column, relation or embedded struct produced by the naming strategy.
+type KeyResolver func(field reflect.StructField, column string) string
+
+// KeyByColumn uses the names produced by the naming strategy, like {"group_name": "X"}. This is the default.
+func KeyByColumn(_ reflect.StructField, column string) string {
+	return column
+}
+
+// KeyByFieldName uses the names of the fields in Go, like {"GroupName": "X"}
+func KeyByFieldName(field reflect.StructField, _ string) string {
+	return field.Name
+}
+
+// KeyByJSONTag uses the names in json tags, like {"groupName": "X"}. Fields without a name in their json tag or
+// with `json:"-"` don't have a key.
+func KeyByJSONTag(field reflect.StructField, _ string) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+
+	return name
+}
+
+// KeyByFilterTag uses explicit aliases in filter tags, like `filter:"group"`
+func KeyByFilterTag(field reflect.StructField, _ string) string {
+	return field.Tag.Get("filter")
+}
+
+// keyedField is a struct field that can be used in filters and the key that's used for it by default
+type keyedField struct {
+	field  reflect.StructField
+	column string
+}
+
+// resolveKeys returns the default keys of the fields by the keys that may be used in filters, refer to WithKeys.
+// If fields share a key, the earlier resolver and then the earlier field takes precedence.
+func (d *DeepGorm) resolveKeys(fields []keyedField) map[string]string {
+	result := make(map[string]string, len(fields))
+
+	for _, resolver := range d.keyResolvers {
+		for _, field := range fields {
+			key := resolver(field.field, field.column)
+			if _, ok := result[key]; key != "" && !ok {
+				result[key] = field.column
+			}
+		}
+	}
+
+	return result
+}
+
+// getKeysOfType godoc
+// Returns the default keys of the columns, relations and embedded structs of the type by the keys that may be used
+// in filters, for example using WithKeys(KeyByJSONTag):
+//
+//	type User struct {
+//		GroupName string `json:"groupName"`
+//		Pets      []Pet  `json:"pets"`
+//	}
+//
+// Returns:
+//
+//	{
+//		"groupName": "group_name",
+//		"pets":      "pets",
+//	}
+//
+// The columns of embedded structs are resolved within their struct, refer to embeddedType.
+func (d *DeepGorm) getKeysOfType(naming schema.Namer, schemaInfo *schema.Schema) map[string]string {
+	key := cacheKey{modelType: ensureConcrete(schemaInfo.ModelType), table: schemaInfo.Table, naming: namingKey(naming)}
+
+	if keys, ok := d.keyCache.Load(key); ok {
+		return keys
+	}
+
+	fields := make([]keyedField, 0, len(schemaInfo.Fields))
+
+	// Columns of embedded structs may only be used by their column name at this level, like {"addr_city": "X"}
+	var embeddedColumns []keyedField
+
+	for _, field := range schemaInfo.Fields {
+		if field.DBName == "" {
+			continue
+		}
+
+		if isEmbeddedColumn(schemaInfo, field) {
+			embeddedColumns = append(embeddedColumns, keyedField{field: field.StructField, column: field.DBName})
+			continue
+		}
+
+		fields = append(fields, keyedField{field: field.StructField, column: field.DBName})
+	}
+
+	// Sorted, so the same field takes precedence every time
+	names := sortedKeys(schemaInfo.Relationships.Relations)
+
+	for _, name := range names {
+		fields = append(fields, keyedField{field: schemaInfo.Relationships.Relations[name].Field.StructField, column: naming.ColumnName(schemaInfo.Table, name)})
+	}
+
+	fields = append(fields, keyedFieldsOf(nil, d.getEmbeddedFieldsOfType(naming, schemaInfo))...)
+
+	result := d.resolveKeys(fields)
+
+	for _, field := range embeddedColumns {
+		for _, resolver := range d.keyResolvers {
+			if _, ok := result[field.column]; !ok && resolver(field.field, field.column) == field.column {
+				result[field.column] = field.column
+			}
+		}
+	}
+
+	d.keyCache.Store(key, result)
+
+	return result
+}
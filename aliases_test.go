@@ -0,0 +1,335 @@
+package deepgorm
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ing-bank/gormtestutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type AliasGroup struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"groupName" filter:"name"`
+}
+
+type AliasGeo struct {
+	Lat float64 `json:"latitude"`
+}
+
+type AliasAddress struct {
+	City string   `json:"cityName"`
+	Geo  AliasGeo `json:"location" gorm:"embedded;embeddedPrefix:geo_"`
+}
+
+type AliasPet struct {
+	ID          uuid.UUID `json:"id"`
+	AliasUserID uuid.UUID `json:"-"`
+	Age         int       `json:"petAge"`
+}
+
+type AliasUser struct {
+	ID      uuid.UUID    `json:"id"`
+	Name    string       `json:"userName" filter:"name"`
+	Secret  string       `json:"-"`
+	GroupID *uuid.UUID   `json:"groupId"`
+	Group   *AliasGroup  `json:"group" filter:"team"`
+	Address AliasAddress `json:"address" gorm:"embedded;embeddedPrefix:addr_"`
+	Pets    []*AliasPet  `json:"pets"`
+}
+
+func TestKeyResolvers_ReturnExpectedKey(t *testing.T) {
+	t.Parallel()
+	type keyed struct {
+		Plain   string
+		Tagged  string `json:"tagged,omitempty" filter:"alias"`
+		Skipped string `json:"-"`
+		Options string `json:",omitempty"`
+	}
+
+	tests := map[string]struct {
+		resolver KeyResolver
+		field    string
+		expected string
+	}{
+		"column": {
+			resolver: KeyByColumn,
+			field:    "Tagged",
+			expected: "tagged_column",
+		},
+		"field name": {
+			resolver: KeyByFieldName,
+			field:    "Tagged",
+			expected: "Tagged",
+		},
+		"json tag": {
+			resolver: KeyByJSONTag,
+			field:    "Tagged",
+			expected: "tagged",
+		},
+		"json tag without tag": {
+			resolver: KeyByJSONTag,
+			field:    "Plain",
+			expected: "",
+		},
+		"json tag that is skipped": {
+			resolver: KeyByJSONTag,
+			field:    "Skipped",
+			expected: "",
+		},
+		"json tag with only options": {
+			resolver: KeyByJSONTag,
+			field:    "Options",
+			expected: "",
+		},
+		"filter tag": {
+			resolver: KeyByFilterTag,
+			field:    "Tagged",
+			expected: "alias",
+		},
+		"filter tag without tag": {
+			resolver: KeyByFilterTag,
+			field:    "Plain",
+			expected: "",
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			field, _ := reflect.TypeOf(keyed{}).FieldByName(testData.field)
+
+			// Act
+			result := testData.resolver(field, "tagged_column")
+
+			// Assert
+			assert.Equal(t, testData.expected, result)
+		})
+	}
+}
+
+func TestDeepGorm_AddDeepFilters_ResolvesKeys(t *testing.T) {
+	t.Parallel()
+	records := []*AliasUser{
+		{
+			ID:      uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+			Name:    "Jake",
+			Group:   &AliasGroup{ID: uuid.New(), Name: "admins"},
+			Address: AliasAddress{City: "Utrecht", Geo: AliasGeo{Lat: 52.1}},
+			Pets:    []*AliasPet{{ID: uuid.New(), Age: 3}, {ID: uuid.New(), Age: 12}},
+		},
+		{
+			ID:      uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+			Name:    "Anna",
+			Address: AliasAddress{City: "Amsterdam", Geo: AliasGeo{Lat: 52.4}},
+			Pets:    []*AliasPet{{ID: uuid.New(), Age: 1}},
+		},
+	}
+
+	tests := map[string]struct {
+		options   []Option
+		filterMap map[string]any
+		expected  []string
+	}{
+		"column names by default": {
+			filterMap: map[string]any{"group": map[string]any{"name": "admins"}, "addr_city": "Utrecht"},
+			expected:  []string{"Jake"},
+		},
+		"field names": {
+			options:   []Option{WithKeys(KeyByFieldName)},
+			filterMap: map[string]any{"Group": map[string]any{"Name": "admins"}, "Address": map[string]any{"City": "Utrecht"}},
+			expected:  []string{"Jake"},
+		},
+		"json tags": {
+			options:   []Option{WithKeys(KeyByJSONTag)},
+			filterMap: map[string]any{"userName": "Anna", "address": map[string]any{"cityName": "Amsterdam"}},
+			expected:  []string{"Anna"},
+		},
+		"json tags of embedded structs in embedded structs": {
+			options:   []Option{WithKeys(KeyByJSONTag)},
+			filterMap: map[string]any{"address": map[string]any{"location": map[string]any{"latitude": map[string]any{"$gt": 52.2}}}},
+			expected:  []string{"Anna"},
+		},
+		"json tags in relations": {
+			options:   []Option{WithKeys(KeyByJSONTag)},
+			filterMap: map[string]any{"group": map[string]any{"groupName": "admins"}},
+			expected:  []string{"Jake"},
+		},
+		"json tags in quantifiers and aggregates": {
+			options: []Option{WithKeys(KeyByJSONTag)},
+			filterMap: map[string]any{"pets": map[string]any{
+				"$all": map[string]any{"petAge": map[string]any{"$lt": 5}},
+				"$max": map[string]any{"petAge": 1},
+			}},
+			expected: []string{"Anna"},
+		},
+		"json tags in logical combinators": {
+			options: []Option{WithKeys(KeyByJSONTag)},
+			filterMap: map[string]any{"$or": []map[string]any{
+				{"userName": "Jake"},
+				{"address": map[string]any{"cityName": "Amsterdam"}},
+			}},
+			expected: []string{"Anna", "Jake"},
+		},
+		"filter tags before json tags": {
+			options:   []Option{WithKeys(KeyByFilterTag, KeyByJSONTag)},
+			filterMap: map[string]any{"team": map[string]any{"name": "admins"}, "pets": map[string]any{"petAge": 12}},
+			expected:  []string{"Jake"},
+		},
+		"column names and json tags": {
+			options:   []Option{WithKeys(KeyByColumn, KeyByJSONTag)},
+			filterMap: map[string]any{"name": "Jake", "group": map[string]any{"groupName": "admins"}},
+			expected:  []string{"Jake"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&AliasUser{}, &AliasGroup{}, &AliasPet{})
+
+			database.CreateInBatches(records, len(records))
+
+			plugin := New(testData.options...)
+
+			// Act
+			query, err := plugin.AddDeepFilters(database, AliasUser{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []string
+				res := query.Model(&AliasUser{}).Order("name").Pluck("name", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, testData.expected, result)
+			}
+		})
+	}
+}
+
+func TestDeepGorm_AddDeepFilters_ReturnsErrorOnUnresolvedKeys(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		options      []Option
+		filterMap    map[string]any
+		expectedPath string
+		expectedMsg  string
+	}{
+		"column name when using json tags": {
+			options:      []Option{WithKeys(KeyByJSONTag)},
+			filterMap:    map[string]any{"name": "Jake"},
+			expectedPath: "name",
+			expectedMsg:  "failed to add filters for 'alias_users.name': field does not exist",
+		},
+		"skipped json tag": {
+			options:      []Option{WithKeys(KeyByJSONTag)},
+			filterMap:    map[string]any{"secret": "abc"},
+			expectedPath: "secret",
+			expectedMsg:  "failed to add filters for 'alias_users.secret': field does not exist",
+		},
+		"column name in relation": {
+			options:      []Option{WithKeys(KeyByJSONTag)},
+			filterMap:    map[string]any{"group": map[string]any{"name": "admins"}},
+			expectedPath: "group.name",
+			expectedMsg:  "failed to add filters for 'alias_groups.name': field does not exist",
+		},
+		"column name in embedded struct": {
+			options:      []Option{WithKeys(KeyByFieldName)},
+			filterMap:    map[string]any{"Address": map[string]any{"city": "Utrecht"}},
+			expectedPath: "Address.city",
+			expectedMsg:  "failed to add filters for 'alias_users.address.city': field does not exist",
+		},
+		"column name in aggregate": {
+			options:      []Option{WithKeys(KeyByJSONTag)},
+			filterMap:    map[string]any{"pets": map[string]any{"$sum": map[string]any{"age": 5}}},
+			expectedPath: "pets.$sum.age",
+			expectedMsg:  "failed to add filters for 'alias_pets.age': field does not exist",
+		},
+		"aliases in path": {
+			options:      []Option{WithKeys(KeyByFieldName)},
+			filterMap:    map[string]any{"Group": map[string]any{"Name": map[string]any{"$like": 5}}},
+			expectedPath: "Group.Name",
+			expectedMsg:  "failed to add filters for 'alias_groups.name': '$like' expects a string: invalid operator value",
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			plugin := New(testData.options...)
+
+			// Act
+			query, err := plugin.AddDeepFilters(database, AliasUser{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, query)
+			assert.EqualError(t, err, testData.expectedMsg)
+
+			var filterError *FilterError
+			require.True(t, errors.As(err, &filterError))
+			assert.Equal(t, testData.expectedPath, filterError.Path)
+		})
+	}
+}
+
+func TestDeepGorm_Initialize_ResolvesKeys(t *testing.T) {
+	t.Parallel()
+	records := []*AliasUser{
+		{ID: uuid.New(), Name: "Jake", Group: &AliasGroup{ID: uuid.New(), Name: "admins"}},
+		{ID: uuid.New(), Name: "Anna"},
+	}
+
+	tests := map[string]struct {
+		filterMap map[string]any
+		expected  []string
+	}{
+		"simple value": {
+			filterMap: map[string]any{"userName": "Anna"},
+			expected:  []string{"Anna"},
+		},
+		"list": {
+			filterMap: map[string]any{"userName": []string{"Anna", "Jake"}},
+			expected:  []string{"Anna", "Jake"},
+		},
+		"relation": {
+			filterMap: map[string]any{"group": map[string]any{"groupName": "admins"}},
+			expected:  []string{"Jake"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&AliasUser{}, &AliasGroup{}, &AliasPet{})
+			_ = database.Use(New(WithKeys(KeyByJSONTag)))
+
+			database.CreateInBatches(records, len(records))
+
+			// Act
+			var result []string
+			res := database.Model(&AliasUser{}).Where(testData.filterMap).Order("name").Pluck("name", &result)
+
+			// Assert
+			assert.Nil(t, res.Error)
+			assert.Equal(t, testData.expected, result)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package deepgorm
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// cacheKey identifies a type, two types with the same name in different packages
+// or tables named by different naming strategies get their own entry.
+type cacheKey struct {
+	modelType reflect.Type
+	table     string
+	naming    any
+}
+
+// namingKey returns the naming strategy if it can be used as a map key, otherwise a description of its settings
+func namingKey(naming schema.Namer) any {
+	if naming == nil || reflect.ValueOf(naming).Comparable() {
+		return naming
+	}
+
+	return fmt.Sprintf("%T%+v", naming, naming)
+}
+
+// parseSchema parses the type with gorm's schema.Parse using the cache of the naming strategy of the database
+func (d *DeepGorm) parseSchema(db *gorm.DB, objectType any) (*schema.Schema, error) {
+	cache, _ := d.schemaCache.LoadOrStore(namingKey(db.NamingStrategy), &sync.Map{})
+
+	return schema.Parse(objectType, cache, db.NamingStrategy)
+}
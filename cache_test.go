@@ -0,0 +1,111 @@
+package deepgorm_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ing-bank/gormtestutil"
+	"github.com/stretchr/testify/assert"
+	deepgorm "github.com/survivorbat/gorm-deep-filtering"
+	"gorm.io/gorm/schema"
+)
+
+// ManyA has the same name and table as deepgorm.ManyA, but different relations
+type ManyA struct {
+	ID     uuid.UUID
+	A      string
+	Owners []*ManyAOwner
+}
+
+type ManyAOwner struct {
+	ID      uuid.UUID
+	Name    string
+	ManyAID uuid.UUID
+}
+
+func TestAddDeepFilters_KeepsSameNamedTypesApart(t *testing.T) {
+	t.Parallel()
+	// Arrange
+	otherDatabase := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()+"_other"))
+	_ = otherDatabase.AutoMigrate(&deepgorm.ManyA{}, &deepgorm.ManyB{})
+
+	database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+	_ = database.AutoMigrate(&ManyA{}, &ManyAOwner{})
+
+	records := []*ManyA{
+		{ID: uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"), A: "first", Owners: []*ManyAOwner{{ID: uuid.New(), Name: "Jessica"}}},
+		{ID: uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"), A: "second", Owners: []*ManyAOwner{{ID: uuid.New(), Name: "Amy"}}},
+	}
+	database.CreateInBatches(records, len(records))
+
+	// Fill the cache with the relations of deepgorm.ManyA
+	_, err := deepgorm.AddDeepFilters(otherDatabase, deepgorm.ManyA{}, map[string]any{"many_bs": map[string]any{"b": "b"}})
+	assert.Nil(t, err)
+
+	// Act
+	query, err := deepgorm.AddDeepFilters(database, ManyA{}, map[string]any{"owners": map[string]any{"name": "Amy"}})
+
+	// Assert
+	assert.Nil(t, err)
+
+	if assert.NotNil(t, query) {
+		var result []string
+		res := query.Model(&ManyA{}).Pluck("a", &result)
+
+		// Handle error
+		assert.Nil(t, res.Error)
+
+		assert.Equal(t, []string{"second"}, result)
+	}
+}
+
+func TestAddDeepFilters_KeepsNamingStrategiesApart(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		naming schema.NamingStrategy
+	}{
+		"default": {
+			naming: schema.NamingStrategy{},
+		},
+		"table prefix": {
+			naming: schema.NamingStrategy{TablePrefix: "tenant_"},
+		},
+		"singular tables": {
+			naming: schema.NamingStrategy{SingularTable: true},
+		},
+	}
+
+	records := []*ManyA{
+		{ID: uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"), A: "first", Owners: []*ManyAOwner{{ID: uuid.New(), Name: "Jessica"}}},
+		{ID: uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"), A: "second", Owners: []*ManyAOwner{{ID: uuid.New(), Name: "Amy"}}},
+	}
+
+	// Not parallel, the point is that every naming strategy uses the cache the previous one left behind
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			database.NamingStrategy = testData.naming
+			_ = database.AutoMigrate(&ManyA{}, &ManyAOwner{})
+
+			database.CreateInBatches(records, len(records))
+
+			// Act
+			query, err := deepgorm.AddDeepFilters(database, ManyA{}, map[string]any{"owners": map[string]any{"name": "Jessica"}})
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []string
+				res := query.Model(&ManyA{}).Pluck("a", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, []string{"first"}, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package deepgorm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// filterChecker goes through a filter before any SQL is built, to find the fields that may not be used and to
+// check the limits, refer to checkFilters
+type filterChecker struct {
+	d  *DeepGorm
+	db *gorm.DB
+
+	// scopes are the policies of the model and the related models the filter is in, refer to allows
+	scopes []policyScope
+
+	// errs are the fields that may not be used
+	errs []error
+
+	// The number of predicates and subqueries so far, refer to Limits
+	predicates int
+	subqueries int
+
+	// limitErr is the first limit that was exceeded, nothing is checked after that
+	limitErr error
+}
+
+// policyScope is the policy of a model in a filter, offset is the length of the path from the root model to it
+type policyScope struct {
+	policy Policy
+	offset int
+}
+
+// checkFilters godoc
+// Returns an error matching ErrFieldNotFilterable for every field in the filters that has the `deepgorm:"-"` tag or
+// that the policy of the model or a related model doesn't allow, refer to Policy. If the filters exceed one of the limits, only a
+// LimitError is returned, refer to Limits. Other problems are left to buildDeepFilters.
+func (d *DeepGorm) checkFilters(db *gorm.DB, objectType any, filters []map[string]any) error {
+	// Invalid models are reported by buildDeepFilters
+	schemaInfo, err := d.parseSchema(db, objectType)
+	if err != nil {
+		return nil
+	}
+
+	checker := &filterChecker{d: d, db: db, scopes: []policyScope{{policy: d.policyOf(schemaInfo)}}}
+
+	for _, filter := range filters {
+		checker.checkFilter(schemaInfo, nil, nil, filter, 0)
+	}
+
+	if checker.limitErr != nil {
+		return checker.limitErr
+	}
+
+	if len(checker.errs) > 0 {
+		return joinErrors(checker.errs)
+	}
+
+	return nil
+}
+
+// checkFilter checks the keys of a filter map on the schema, columns are the path of default keys and keys the path
+// of keys as they were given. The depth is the number of filter maps this one is nested in.
+func (c *filterChecker) checkFilter(schemaInfo *schema.Schema, columns []string, keys []string, filter map[string]any, depth int) {
+	if c.exceeds(LimitDepth, c.d.limits.MaxDepth, depth, keys) {
+		return
+	}
+
+	naming := c.db.NamingStrategy
+	resolved := c.d.getKeysOfType(naming, schemaInfo)
+
+	for _, key := range sortedKeys(filter) {
+		if c.limitErr != nil {
+			return
+		}
+
+		value := filter[key]
+
+		// Logical combinators like {"$or": [...]} are about the same schema
+		if strings.HasPrefix(key, operatorPrefix) {
+			c.checkLogical(schemaInfo, columns, appendPath(keys, key), value, depth+1)
+			continue
+		}
+
+		column, ok := resolved[key]
+		if !ok {
+			continue
+		}
+
+		path := appendPath(columns, column)
+		given := appendPath(keys, key)
+
+		if c.d.isHiddenColumn(naming, schemaInfo, column) || !c.allows(path) {
+			c.addError(schemaInfo, column, path, given, value)
+			continue
+		}
+
+		nestedFilter, isMap := value.(map[string]any)
+
+		if fieldInfo, ok := c.d.getDatabaseFieldsOfType(naming, schemaInfo)[column]; ok && (isMap || value == nil) {
+			c.checkRelation(fieldInfo, path, given, nestedFilter, depth+1)
+			continue
+		}
+
+		if embedded, ok := c.d.getEmbeddedFieldsOfType(naming, schemaInfo)[column]; ok && isMap {
+			c.checkEmbedded(schemaInfo, embedded, path, given, nestedFilter, depth+1)
+			continue
+		}
+
+		c.checkValue(given, value, depth)
+	}
+}
+
+// checkLogical checks the filter maps of $and, $or and $not
+func (c *filterChecker) checkLogical(schemaInfo *schema.Schema, columns []string, keys []string, value any, depth int) {
+	if filter, ok := value.(map[string]any); ok {
+		c.checkFilter(schemaInfo, columns, keys, filter, depth)
+		return
+	}
+
+	filters, err := toFilterList(value)
+	if err != nil {
+		return
+	}
+
+	for index, filter := range filters {
+		c.checkFilter(schemaInfo, columns, appendPath(keys, strconv.Itoa(index)), filter, depth)
+	}
+}
+
+// checkRelation checks the filter on a relation, including the filters of quantifiers and the columns of aggregates.
+// The filter is nil for {"group": nil}, refer to buildRelationNullExpression.
+func (c *filterChecker) checkRelation(fieldInfo *nestedType, columns []string, keys []string, filter map[string]any, depth int) {
+	if filter == nil {
+		c.addPredicates(keys, 1)
+
+		// The foreign key of a belongs-to relation is in our own table
+		if fieldInfo.relationType != "oneToMany" {
+			c.addSubqueries(keys, 1)
+		}
+
+		return
+	}
+
+	relatedSchema, err := c.d.parseSchema(c.db, fieldInfo.fieldStructInstance)
+	if err != nil {
+		return
+	}
+
+	// The policy of the related model applies to everything below the relation
+	c.scopes = append(c.scopes, policyScope{policy: c.d.policyOf(relatedSchema), offset: len(columns)})
+	defer func() { c.scopes = c.scopes[:len(c.scopes)-1] }()
+
+	unquantified := map[string]any{}
+
+	for key, value := range filter {
+		if !relationOperatorNames[key] {
+			unquantified[key] = value
+		}
+	}
+
+	// The same order as buildRelationExpression, the regular filters come first
+	if relationOperators := len(filter) - len(unquantified); len(unquantified) > 0 || relationOperators == 0 {
+		c.addSubqueries(keys, 1)
+		c.checkFilter(relatedSchema, columns, keys, unquantified, depth)
+	}
+
+	for _, key := range sortedKeys(filter) {
+		nestedFilter, isMap := filter[key].(map[string]any)
+		operatorKeys := appendPath(keys, key)
+
+		switch key {
+		case "$any", "$all", "$none":
+			c.addSubqueries(operatorKeys, 1)
+
+			if isMap {
+				c.checkFilter(relatedSchema, columns, operatorKeys, nestedFilter, depth+1)
+			}
+
+		case "$sum", "$avg", "$min", "$max":
+			if isMap {
+				c.checkAggregate(relatedSchema, columns, operatorKeys, nestedFilter, depth+1)
+			}
+
+		case "$count":
+			c.addSubqueries(operatorKeys, 1)
+			c.checkValue(operatorKeys, filter[key], depth)
+
+		case "$exists", "$notNull":
+			if fieldInfo.relationType != "oneToMany" {
+				c.addSubqueries(operatorKeys, 1)
+			}
+
+			c.addPredicates(operatorKeys, 1)
+		}
+	}
+}
+
+// checkAggregate checks the columns of an aggregate like {"$sum": {"amount": ...}}, every column is a subquery
+func (c *filterChecker) checkAggregate(relatedSchema *schema.Schema, columns []string, keys []string, filter map[string]any, depth int) {
+	if c.exceeds(LimitDepth, c.d.limits.MaxDepth, depth, keys) {
+		return
+	}
+
+	resolved := c.d.getKeysOfType(c.db.NamingStrategy, relatedSchema)
+
+	for _, key := range sortedKeys(filter) {
+		column, ok := resolved[key]
+		if !ok {
+			continue
+		}
+
+		path := appendPath(columns, column)
+		given := appendPath(keys, key)
+
+		if c.d.isHiddenColumn(c.db.NamingStrategy, relatedSchema, column) || !c.allows(path) {
+			c.addError(relatedSchema, column, path, given, filter[key])
+			continue
+		}
+
+		c.addSubqueries(given, 1)
+		c.checkValue(given, filter[key], depth)
+	}
+}
+
+// checkEmbedded checks the filter on an embedded struct, the path contains the names of the columns without prefix
+func (c *filterChecker) checkEmbedded(schemaInfo *schema.Schema, embedded *embeddedType, columns []string, keys []string, filter map[string]any, depth int) {
+	if c.exceeds(LimitDepth, c.d.limits.MaxDepth, depth, keys) {
+		return
+	}
+
+	for _, key := range sortedKeys(filter) {
+		name, ok := embedded.keys[key]
+		if !ok {
+			continue
+		}
+
+		path := appendPath(columns, name)
+		given := appendPath(keys, key)
+		nested, isEmbedded := embedded.embedded[name]
+
+		hidden, column := isEmbedded && hasHiddenTag(nested.field), name
+		if field, isColumn := embedded.columns[name]; isColumn {
+			hidden, column = isHidden(schemaInfo, field), field.DBName
+		}
+
+		if hidden || !c.allows(path) {
+			c.addError(schemaInfo, column, path, given, filter[key])
+			continue
+		}
+
+		if nestedFilter, isMap := filter[key].(map[string]any); isMap && isEmbedded {
+			c.checkEmbedded(schemaInfo, nested, path, given, nestedFilter, depth+1)
+			continue
+		}
+
+		c.checkValue(given, filter[key], depth)
+	}
+}
+
+// allows returns whether the path may be used according to the policy of the model and the policies of the related
+// models on the path, every policy gets the part of the path below its model
+func (c *filterChecker) allows(path []string) bool {
+	for _, scope := range c.scopes {
+		if !scope.policy.allows(strings.Join(path[scope.offset:], ".")) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkValue counts the conditions in the value of a column: a value, a map of operators or objects in a JSON column
+func (c *filterChecker) checkValue(keys []string, value any, depth int) {
+	filter, isMap := value.(map[string]any)
+
+	if !isMap {
+		c.checkList(keys, value)
+		c.addPredicates(keys, 1)
+
+		return
+	}
+
+	if isOperatorMap(filter) {
+		for _, key := range sortedKeys(filter) {
+			c.checkList(appendPath(keys, key), filter[key])
+			c.addPredicates(keys, 1)
+		}
+
+		return
+	}
+
+	// Objects in JSON columns, like {"metadata": {"labels": {"env": "prod"}}}
+	if c.exceeds(LimitDepth, c.d.limits.MaxDepth, depth+1, keys) {
+		return
+	}
+
+	for _, key := range sortedKeys(filter) {
+		c.checkValue(appendPath(keys, key), filter[key], depth+1)
+	}
+}
+
+// checkList checks the size of lists like {"id": [1, 2, 3]}
+func (c *filterChecker) checkList(keys []string, value any) {
+	c.exceeds(LimitListSize, c.d.limits.MaxListSize, listSize(value), keys)
+}
+
+// addPredicates adds conditions to the total, refer to Limits.MaxPredicates
+func (c *filterChecker) addPredicates(keys []string, count int) {
+	c.predicates += count
+	c.exceeds(LimitPredicates, c.d.limits.MaxPredicates, c.predicates, keys)
+}
+
+// addSubqueries adds subqueries to the total, refer to Limits.MaxSubqueries
+func (c *filterChecker) addSubqueries(keys []string, count int) {
+	c.subqueries += count
+	c.exceeds(LimitSubqueries, c.d.limits.MaxSubqueries, c.subqueries, keys)
+}
+
+// exceeds returns true if a limit was exceeded before or if the value exceeds the limit, limits that are 0 aren't
+// checked. Only the first limit that is exceeded is reported.
+func (c *filterChecker) exceeds(kind LimitKind, limit int, value int, keys []string) bool {
+	if c.limitErr != nil {
+		return true
+	}
+
+	if limit <= 0 || value <= limit {
+		return false
+	}
+
+	c.limitErr = &LimitError{Kind: kind, Max: limit, Path: strings.Join(keys, ".")}
+
+	return true
+}
+
+// addError adds a FilterError matching ErrFieldNotFilterable for the field
+func (c *filterChecker) addError(schemaInfo *schema.Schema, field string, columns []string, keys []string, value any) {
+	filterError := newFilterError(schemaInfo, field, value, fmt.Errorf("'%s' can't be used in filters: %w", strings.Join(columns, "."), ErrFieldNotFilterable))
+	filterError.Path = strings.Join(keys, ".")
+
+	c.errs = append(c.errs, filterError)
+}
+
+// appendPath returns a copy of the path with the keys added to it, so paths of different keys don't share memory
+func appendPath(path []string, keys ...string) []string {
+	result := make([]string, 0, len(path)+len(keys))
+
+	return append(append(result, path...), keys...)
+}
@@ -0,0 +1,243 @@
+package deepgorm
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm/schema"
+)
+
+// ErrTypeMismatch is returned if a value in a filter can't be converted to the type of the field
+var ErrTypeMismatch = errors.New("type mismatch")
+
+// timeType is converted from RFC3339 strings
+var timeType = reflect.TypeOf(time.Time{})
+
+// coercer converts a value in a filter, refer to coerceValue
+type coercer func(value any) (any, error)
+
+// coercerOf returns a coercer that converts values to the type of the field
+func coercerOf(field *schema.Field) coercer {
+	return func(value any) (any, error) {
+		return coerceValue(field, value)
+	}
+}
+
+// coercerOfType returns a coercer that converts values to the type, used for values that aren't compared with a field
+// like the results of $count and $sum
+func coercerOfType(targetType reflect.Type) coercer {
+	return func(value any) (any, error) {
+		return coerceToType(targetType, value)
+	}
+}
+
+// coerceValue godoc
+// Converts the value in a filter to the type of the field, values from JSON and query strings are usually strings
+// and float64s. Lists are converted value by value, for example:
+//
+//	type User struct {
+//		Age       int
+//		CreatedAt time.Time
+//	}
+//
+//	map[string]any{
+//		"age":        "42",                                  // 42
+//		"created_at": map[string]any{"$gt": "2023-01-02T15:04:05Z"}, // time.Time
+//	}
+//
+// Values of types that implement sql.Scanner, like uuid.UUID, are converted using Scan, types like gorm.DeletedAt
+// are converted from RFC3339 strings as well. Columns that use a serializer or contain JSON are left alone, just like
+// values that already have the type of the field.
+func coerceValue(field *schema.Field, value any) (any, error) {
+	if field.Serializer != nil || isJSONColumn(field) {
+		return value, nil
+	}
+
+	return coerceToType(ensureConcrete(field.FieldType), value)
+}
+
+// coerceToType converts the value or the values in a list to the type, refer to coerceValue
+func coerceToType(targetType reflect.Type, value any) (any, error) {
+	reflectValue := reflect.ValueOf(value)
+
+	// Lists like {"name": ["a", "b"]} or {"$between": [1, 2]}, unless the field itself is a list like []byte
+	if kind := reflectValue.Kind(); (kind == reflect.Slice || kind == reflect.Array) && !reflectValue.Type().ConvertibleTo(targetType) {
+		result := make([]any, reflectValue.Len())
+
+		for index := range result {
+			converted, err := convertValue(targetType, reflectValue.Index(index).Interface())
+			if err != nil {
+				return nil, err
+			}
+
+			result[index] = converted
+		}
+
+		return result, nil
+	}
+
+	return convertValue(targetType, value)
+}
+
+// convertValue converts a single value to the type, refer to coerceValue
+func convertValue(targetType reflect.Type, value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	source := reflect.ValueOf(value)
+	if ensureConcrete(source.Type()) == targetType {
+		return value, nil
+	}
+
+	// Only values like the ones from JSON and query strings are converted, others like subqueries are up to the database
+	switch source.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return value, nil
+	}
+
+	mismatch := fmt.Errorf("can't convert '%v' (%T) to %s: %w", value, value, targetType, ErrTypeMismatch)
+
+	// Types like uuid.UUID and sql.NullString know how to convert values themselves
+	if scanner, ok := reflect.New(targetType).Interface().(sql.Scanner); ok {
+		if err := scanner.Scan(value); err != nil {
+			return scanTime(targetType, value, mismatch)
+		}
+
+		return reflect.ValueOf(scanner).Elem().Interface(), nil
+	}
+
+	result := reflect.New(targetType).Elem()
+
+	switch {
+	case targetType == timeType:
+		text, ok := value.(string)
+		if !ok {
+			return nil, mismatch
+		}
+
+		parsed, err := time.Parse(time.RFC3339, text)
+		if err != nil {
+			return nil, mismatch
+		}
+
+		result.Set(reflect.ValueOf(parsed))
+
+	case result.CanInt():
+		number, ok := toInt(source)
+		if !ok || result.OverflowInt(number) {
+			return nil, mismatch
+		}
+
+		result.SetInt(number)
+
+	case result.CanUint():
+		number, ok := toInt(source)
+		if !ok || number < 0 || result.OverflowUint(uint64(number)) {
+			return nil, mismatch
+		}
+
+		result.SetUint(uint64(number))
+
+	case result.CanFloat():
+		number, ok := toFloat(source)
+		if !ok || result.OverflowFloat(number) {
+			return nil, mismatch
+		}
+
+		result.SetFloat(number)
+
+	case targetType.Kind() == reflect.Bool:
+		switch source.Kind() {
+		case reflect.Bool:
+			result.SetBool(source.Bool())
+		case reflect.String:
+			parsed, err := strconv.ParseBool(source.String())
+			if err != nil {
+				return nil, mismatch
+			}
+
+			result.SetBool(parsed)
+		default:
+			return nil, mismatch
+		}
+
+	// Strings and enums like 'type Status string'
+	case targetType.Kind() == reflect.String:
+		if source.Kind() != reflect.String {
+			return nil, mismatch
+		}
+
+		result.SetString(source.String())
+
+	// Other types are up to the database
+	default:
+		return value, nil
+	}
+
+	return result.Interface(), nil
+}
+
+// scanTime converts RFC3339 strings for types like gorm.DeletedAt and sql.NullTime, which only scan a time.Time.
+// The mismatch is returned if the value isn't a time.
+func scanTime(targetType reflect.Type, value any, mismatch error) (any, error) {
+	text, ok := value.(string)
+	if !ok {
+		return nil, mismatch
+	}
+
+	parsed, err := time.Parse(time.RFC3339, text)
+	if err != nil {
+		return nil, mismatch
+	}
+
+	scanner := reflect.New(targetType).Interface().(sql.Scanner)
+	if err := scanner.Scan(parsed); err != nil {
+		return nil, mismatch
+	}
+
+	return reflect.ValueOf(scanner).Elem().Interface(), nil
+}
+
+// toFloat returns the number in the value, which may be a number or a string containing one
+func toFloat(value reflect.Value) (float64, bool) {
+	switch {
+	case value.CanInt():
+		return float64(value.Int()), true
+	case value.CanUint():
+		return float64(value.Uint()), true
+	case value.CanFloat():
+		return value.Float(), true
+	case value.Kind() == reflect.String:
+		number, err := strconv.ParseFloat(value.String(), 64)
+		return number, err == nil
+	}
+
+	return 0, false
+}
+
+// toInt returns the whole number in the value, which may be a number or a string containing one
+func toInt(value reflect.Value) (int64, bool) {
+	switch {
+	case value.CanInt():
+		return value.Int(), true
+	case value.CanUint():
+		return int64(value.Uint()), value.Uint() <= math.MaxInt64
+	case value.CanFloat():
+		number := value.Float()
+		return int64(number), number == math.Trunc(number) && number >= math.MinInt64 && number < math.MaxInt64
+	case value.Kind() == reflect.String:
+		number, err := strconv.ParseInt(value.String(), 10, 64)
+		return number, err == nil
+	}
+
+	return 0, false
+}
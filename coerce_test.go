@@ -0,0 +1,452 @@
+package deepgorm
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ing-bank/gormtestutil"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+type CoerceStatus string
+
+type CoerceLevel int
+
+type CoerceStruct struct {
+	ID        uuid.UUID
+	ParentID  *uuid.UUID
+	Age       int
+	Size      uint8
+	Score     float64
+	Active    bool
+	Status    CoerceStatus
+	Level     CoerceLevel
+	Nickname  sql.NullString
+	Name      string
+	CreatedAt time.Time
+	DeletedAt gorm.DeletedAt
+	Avatar    []byte
+	Metadata  map[string]any `gorm:"serializer:json"`
+}
+
+func TestCoerceValue_ReturnsExpectedValue(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		field    string
+		value    any
+		expected any
+	}{
+		"int from string": {
+			field:    "age",
+			value:    "42",
+			expected: 42,
+		},
+		"int from float": {
+			field:    "age",
+			value:    float64(42),
+			expected: 42,
+		},
+		"uint from string": {
+			field:    "size",
+			value:    "255",
+			expected: uint8(255),
+		},
+		"float from string": {
+			field:    "score",
+			value:    "4.5",
+			expected: 4.5,
+		},
+		"float from int": {
+			field:    "score",
+			value:    4,
+			expected: float64(4),
+		},
+		"bool from string": {
+			field:    "active",
+			value:    "true",
+			expected: true,
+		},
+		"string enum": {
+			field:    "status",
+			value:    "active",
+			expected: CoerceStatus("active"),
+		},
+		"int enum": {
+			field:    "level",
+			value:    "3",
+			expected: CoerceLevel(3),
+		},
+		"uuid from string": {
+			field:    "id",
+			value:    "59aa5a8f-c5de-44fa-9355-080650481687",
+			expected: uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+		},
+		"uuid pointer from string": {
+			field:    "parent_id",
+			value:    "59aa5a8f-c5de-44fa-9355-080650481687",
+			expected: uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+		},
+		"scanner": {
+			field:    "nickname",
+			value:    "Jake",
+			expected: sql.NullString{String: "Jake", Valid: true},
+		},
+		"time from string": {
+			field:    "created_at",
+			value:    "2023-01-02T15:04:05Z",
+			expected: time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC),
+		},
+		"deleted at from string": {
+			field:    "deleted_at",
+			value:    "2023-01-02T15:04:05Z",
+			expected: gorm.DeletedAt{Time: time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC), Valid: true},
+		},
+		"list": {
+			field:    "age",
+			value:    []any{"1", float64(2)},
+			expected: []any{1, 2},
+		},
+		"bytes": {
+			field:    "avatar",
+			value:    []byte("abc"),
+			expected: []byte("abc"),
+		},
+		"same type": {
+			field:    "age",
+			value:    5,
+			expected: 5,
+		},
+		"nil": {
+			field:    "age",
+			value:    nil,
+			expected: nil,
+		},
+		"serializer": {
+			field:    "metadata",
+			value:    "abc",
+			expected: "abc",
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			schemaInfo, _ := schema.Parse(&CoerceStruct{}, &sync.Map{}, schema.NamingStrategy{})
+
+			// Act
+			result, err := coerceValue(schemaInfo.FieldsByDBName[testData.field], testData.value)
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, testData.expected, result)
+		})
+	}
+}
+
+func TestCoerceValue_ReturnsErrorOnInvalidValue(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		field       string
+		value       any
+		expectedMsg string
+	}{
+		"int from text": {
+			field:       "age",
+			value:       "abc",
+			expectedMsg: "can't convert 'abc' (string) to int: type mismatch",
+		},
+		"int from fraction": {
+			field:       "age",
+			value:       4.5,
+			expectedMsg: "can't convert '4.5' (float64) to int: type mismatch",
+		},
+		"uint overflow": {
+			field:       "size",
+			value:       "256",
+			expectedMsg: "can't convert '256' (string) to uint8: type mismatch",
+		},
+		"negative uint": {
+			field:       "size",
+			value:       -1,
+			expectedMsg: "can't convert '-1' (int) to uint8: type mismatch",
+		},
+		"bool from text": {
+			field:       "active",
+			value:       "yes please",
+			expectedMsg: "can't convert 'yes please' (string) to bool: type mismatch",
+		},
+		"string from number": {
+			field:       "name",
+			value:       float64(5),
+			expectedMsg: "can't convert '5' (float64) to string: type mismatch",
+		},
+		"uuid from text": {
+			field:       "id",
+			value:       "abc",
+			expectedMsg: "can't convert 'abc' (string) to uuid.UUID: type mismatch",
+		},
+		"time from text": {
+			field:       "created_at",
+			value:       "yesterday",
+			expectedMsg: "can't convert 'yesterday' (string) to time.Time: type mismatch",
+		},
+		"deleted at from text": {
+			field:       "deleted_at",
+			value:       "yesterday",
+			expectedMsg: "can't convert 'yesterday' (string) to gorm.DeletedAt: type mismatch",
+		},
+		"list with invalid value": {
+			field:       "age",
+			value:       []any{"1", "two"},
+			expectedMsg: "can't convert 'two' (string) to int: type mismatch",
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			schemaInfo, _ := schema.Parse(&CoerceStruct{}, &sync.Map{}, schema.NamingStrategy{})
+
+			// Act
+			result, err := coerceValue(schemaInfo.FieldsByDBName[testData.field], testData.value)
+
+			// Assert
+			assert.Nil(t, result)
+			assert.ErrorIs(t, err, ErrTypeMismatch)
+			assert.EqualError(t, err, testData.expectedMsg)
+		})
+	}
+}
+
+func TestAddDeepFilters_CoercesValues(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	records := []*CoerceStruct{
+		{ID: uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"), Name: "first", Age: 42, Level: 1, CreatedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"), Name: "second", Age: 18, Level: 2, CreatedAt: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	tests := map[string]struct {
+		filterMap map[string]any
+		expected  []string
+	}{
+		"string on int": {
+			filterMap: map[string]any{"age": "42"},
+			expected:  []string{"first"},
+		},
+		"string on uuid": {
+			filterMap: map[string]any{"id": "23292d51-4768-4c41-8475-6d4c9f0c6f69"},
+			expected:  []string{"second"},
+		},
+		"operator on time": {
+			filterMap: map[string]any{"created_at": map[string]any{"$gt": "2023-03-01T00:00:00Z"}},
+			expected:  []string{"second"},
+		},
+		"between on enum": {
+			filterMap: map[string]any{"level": map[string]any{"$between": []any{"0", "1"}}},
+			expected:  []string{"first"},
+		},
+		"list on int": {
+			filterMap: map[string]any{"age": []any{"18", "42"}},
+			expected:  []string{"first", "second"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&CoerceStruct{})
+
+			database.CreateInBatches(records, len(records))
+
+			// Act
+			query, err := AddDeepFilters(database, CoerceStruct{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []string
+				res := query.Model(&CoerceStruct{}).Order("name").Pluck("name", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, testData.expected, result)
+			}
+		})
+	}
+}
+
+func TestAddDeepFilters_ReturnsErrorOnInvalidValues(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	tests := map[string]struct {
+		model        any
+		filterMap    map[string]any
+		expectedPath string
+		expectedMsg  string
+	}{
+		"simple": {
+			model:        CoerceStruct{},
+			filterMap:    map[string]any{"id": "abc"},
+			expectedPath: "id",
+			expectedMsg:  "failed to add filters for 'coerce_structs.id': can't convert 'abc' (string) to uuid.UUID: type mismatch",
+		},
+		"operator": {
+			model:        CoerceStruct{},
+			filterMap:    map[string]any{"age": map[string]any{"$gt": "old"}},
+			expectedPath: "age",
+			expectedMsg:  "failed to add filters for 'coerce_structs.age': can't convert 'old' (string) to int: type mismatch",
+		},
+		"relation": {
+			model:        ComplexStruct1{},
+			filterMap:    map[string]any{"nested": map[string]any{"id": "abc"}},
+			expectedPath: "nested.id",
+			expectedMsg:  "failed to add filters for 'nested_struct4.id': can't convert 'abc' (string) to uuid.UUID: type mismatch",
+		},
+		"embedded struct": {
+			model:        EmbeddedPerson{},
+			filterMap:    map[string]any{"address": map[string]any{"geo": map[string]any{"lat": "north"}}},
+			expectedPath: "address.geo.lat",
+			expectedMsg:  "failed to add filters for 'embedded_people.addr_geo_lat': can't convert 'north' (string) to float64: type mismatch",
+		},
+		"aggregate": {
+			model:        Invoice{},
+			filterMap:    map[string]any{"line_items": map[string]any{"$max": map[string]any{"due_date": "tomorrow"}}},
+			expectedPath: "line_items.$max.due_date",
+			expectedMsg:  "failed to add filters for 'line_items.due_date': can't convert 'tomorrow' (string) to time.Time: type mismatch",
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+
+			// Act
+			query, err := AddDeepFilters(database, testData.model, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, query)
+			assert.ErrorIs(t, err, ErrTypeMismatch)
+			assert.EqualError(t, err, testData.expectedMsg)
+
+			var filterError *FilterError
+			if assert.True(t, errors.As(err, &filterError)) {
+				assert.Equal(t, KindTypeMismatch, filterError.Kind)
+				assert.Equal(t, testData.expectedPath, filterError.Path)
+			}
+		})
+	}
+}
+
+func TestDeepGorm_Initialize_CoercesValues(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	records := []*CoerceStruct{
+		{ID: uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"), Name: "first", Age: 42},
+		{ID: uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"), Name: "second", Age: 18},
+	}
+
+	tests := map[string]struct {
+		filterMap map[string]any
+		expected  []string
+	}{
+		"string on int": {
+			filterMap: map[string]any{"age": "42"},
+			expected:  []string{"first"},
+		},
+		"string on uuid": {
+			filterMap: map[string]any{"id": "23292d51-4768-4c41-8475-6d4c9f0c6f69"},
+			expected:  []string{"second"},
+		},
+		"list on int": {
+			filterMap: map[string]any{"age": []any{"18", "42"}},
+			expected:  []string{"first", "second"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&CoerceStruct{})
+
+			database.CreateInBatches(records, len(records))
+
+			_ = database.Use(New())
+
+			// Act
+			var result []string
+			err := database.Model(&CoerceStruct{}).Where(testData.filterMap).Order("name").Pluck("name", &result).Error
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, testData.expected, result)
+		})
+	}
+}
+
+func TestDeepGorm_Initialize_ReturnsErrorOnInvalidValues(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	tests := map[string]struct {
+		model       any
+		filterMap   map[string]any
+		expectedMsg string
+	}{
+		"uuid": {
+			model:       &SelfUser{},
+			filterMap:   map[string]any{"id": "not-a-uuid"},
+			expectedMsg: "failed to add filters for 'self_users.id': can't convert 'not-a-uuid' (string) to uuid.UUID: type mismatch",
+		},
+		"column with table": {
+			model:       &SelfUser{},
+			filterMap:   map[string]any{"self_users.id": "not-a-uuid"},
+			expectedMsg: "failed to add filters for 'self_users.id': can't convert 'not-a-uuid' (string) to uuid.UUID: type mismatch",
+		},
+		"list": {
+			model:       &CoerceStruct{},
+			filterMap:   map[string]any{"age": []any{"18", "old"}},
+			expectedMsg: "failed to add filters for 'coerce_structs.age': can't convert 'old' (string) to int: type mismatch",
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(testData.model)
+
+			_ = database.Use(New())
+
+			// Act
+			var result []string
+			err := database.Model(testData.model).Where(testData.filterMap).Pluck("name", &result).Error
+
+			// Assert
+			assert.ErrorIs(t, err, ErrTypeMismatch)
+			assert.EqualError(t, err, testData.expectedMsg)
+		})
+	}
+}
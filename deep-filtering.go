@@ -4,28 +4,26 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
-	"sync"
+	"sort"
+	"strings"
 
-	"github.com/survivorbat/go-tsyncmap"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/schema"
 
 	"gorm.io/gorm"
 )
 
 var (
-	// Cache mechanism for reflecting database structs, reflection is slow, so we
-	// cache results for quick lookups. Just remember to reset it in unit tests ;-)
-
-	// cacheDatabaseMap map[string]map[string]*nestedType{}
-	cacheDatabaseMap = tsyncmap.Map[string, map[string]*nestedType]{}
-
-	// schemaCache is for gorm's schema.Parse
-	schemaCache = sync.Map{}
-
 	// ErrFieldDoesNotExist is returned if the Where condition contains unknown fields
 	ErrFieldDoesNotExist = errors.New("field does not exist")
 )
 
+// AddDeepFilters adds the filters to the query using an instance with the default configuration and caches,
+// refer to DeepGorm.AddDeepFilters.
+func AddDeepFilters(db *gorm.DB, objectType any, filters ...map[string]any) (*gorm.DB, error) {
+	return defaultDeepGorm.AddDeepFilters(db, objectType, filters...)
+}
+
 // AddDeepFilters / addDeepFilter godoc
 //
 // Gorm supports the following filtering:
@@ -69,68 +67,214 @@ var (
 //
 //  1. Get all the struct-type fields from the incoming 'object', ignore all simple types and interfaces
 //  2. Loop through all the key/values in the incoming map
-//  3. Add all the simple types to a simpleMap, GORM can handle these,
+//  3. Add all the simple types as conditions, GORM can handle these,
 //     For all the special (nested) structs, add a subquery that uses WHERE on the subquery.
-//  4. Add the simple filters to the query and return it.
-func AddDeepFilters(db *gorm.DB, objectType any, filters ...map[string]any) (*gorm.DB, error) {
-	schemaInfo, err := schema.Parse(objectType, &schemaCache, db.NamingStrategy)
+//     Maps of operators on simple fields ("age": map[string]any{"$gt": 18}) are turned into comparisons.
+//     Maps on embedded structs ("address": map[string]any{"city": "Utrecht"}) are conditions on our own columns.
+//     Maps on JSON columns ("metadata": map[string]any{"env": "prod"}) are conditions on paths in the document.
+//     Logical combinators ("$or", "$and" and "$not") are built recursively using the same steps.
+//  4. Combine all conditions using AND, add them to the query and return it.
+//
+// Fields that may not be used and filters that are too large are found before anything is built, refer to Policy and
+// Limits. Only the first problem in the order of the keys is returned, whether it's a field that may not be used or
+// another problem, unless the instance was created using WithAllErrors. In that case all of them are returned.
+func (d *DeepGorm) AddDeepFilters(db *gorm.DB, objectType any, filters ...map[string]any) (*gorm.DB, error) {
+	err := d.checkFilters(db, objectType, filters)
+
+	// Nothing is built if a filter is too large
+	var limitError *LimitError
+	if errors.As(err, &limitError) {
+		return nil, err
+	}
+
+	result, buildErr := d.buildDeepFilters(db, objectType, filters...)
+
+	err = mergeErrors(err, buildErr)
+	if err != nil && !d.allErrors {
+		return nil, firstError(err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// buildDeepFilters adds the filters to the query, refer to AddDeepFilters. Returns all the problems in the filters.
+func (d *DeepGorm) buildDeepFilters(db *gorm.DB, objectType any, filters ...map[string]any) (*gorm.DB, error) {
+	schemaInfo, err := d.parseSchema(db, objectType)
 	if err != nil {
 		return nil, err
 	}
 
-	relationalTypesInfo := getDatabaseFieldsOfType(db.NamingStrategy, schemaInfo)
+	expressions := make([]clause.Expression, 0, len(filters))
 
-	simpleFilter := map[string]any{}
+	var errs []error
 
 	// Go through the filters
 	for _, filterObject := range filters {
-		// Go through all the keys of the filters
-		for fieldName, givenFilter := range filterObject {
-			switch givenFilter.(type) {
-			// WithFilters for relational objects
-			case map[string]any:
-				fieldInfo, ok := relationalTypesInfo[fieldName]
-
-				if !ok {
-					return nil, fmt.Errorf("failed to add filters for '%s.%s': %w", schemaInfo.Table, fieldName, ErrFieldDoesNotExist)
-				}
-
-				// We have 2 db objects because if we use 'result' to create subqueries it will cause a stackoverflow.
-				query, err := addDeepFilter(db, fieldInfo, givenFilter)
-				if err != nil {
-					return nil, err
-				}
-
-				db = query
-
-			// Simple filters (string, int, bool etc.)
-			default:
-				if _, ok := schemaInfo.FieldsByDBName[fieldName]; !ok {
-					return nil, fmt.Errorf("failed to add filters for '%s.%s': %w", schemaInfo.Table, fieldName, ErrFieldDoesNotExist)
-				}
-				simpleFilter[schemaInfo.Table+"."+fieldName] = givenFilter
-			}
+		expression, err := d.buildFilterExpression(db, schemaInfo, filterObject)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if expression != nil {
+			expressions = append(expressions, expression)
 		}
 	}
 
-	// Add simple filters
-	db = db.Where(simpleFilter)
+	if len(errs) > 0 {
+		return nil, joinErrors(errs)
+	}
+
+	if len(expressions) == 0 {
+		return db, nil
+	}
 
-	return db, nil
+	// Add all filters as a single condition, the plugin relies on this
+	return db.Where(clause.And(expressions...)), nil
+}
+
+// buildFilterExpression turns a single filter map into one expression, all the keys are combined using AND. Keys
+// are processed in sorted order to get a predictable query. The errors of all the keys are returned, refer to
+// WithAllErrors.
+func (d *DeepGorm) buildFilterExpression(db *gorm.DB, schemaInfo *schema.Schema, filterObject map[string]any) (clause.Expression, error) {
+	relationalTypesInfo := d.getDatabaseFieldsOfType(db.NamingStrategy, schemaInfo)
+	columns := d.getKeysOfType(db.NamingStrategy, schemaInfo)
+
+	fieldNames := sortedKeys(filterObject)
+
+	expressions := make([]clause.Expression, 0, len(fieldNames))
+
+	var errs []error
+
+	// Go through all the keys of the filters
+	for _, fieldName := range fieldNames {
+		// Keys like {"groupName": "X"} refer to a column, relation or embedded struct, refer to WithKeys
+		column, ok := columns[fieldName]
+		if !ok && !strings.HasPrefix(fieldName, operatorPrefix) {
+			errs = append(errs, newFilterError(schemaInfo, fieldName, filterObject[fieldName], ErrFieldDoesNotExist))
+			continue
+		}
+
+		if !ok {
+			column = fieldName
+		}
+
+		expression, err := d.buildFieldExpression(db, schemaInfo, relationalTypesInfo, column, filterObject[fieldName])
+		if err != nil {
+			errs = append(errs, renamePath(err, column, fieldName))
+			continue
+		}
+
+		if expression != nil {
+			expressions = append(expressions, expression)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, joinErrors(errs)
+	}
+
+	return clause.And(expressions...), nil
+}
+
+// buildFieldExpression turns a single key of a filter map into an expression, refer to buildFilterExpression
+func (d *DeepGorm) buildFieldExpression(db *gorm.DB, schemaInfo *schema.Schema, relationalTypesInfo map[string]*nestedType, fieldName string, givenFilter any) (clause.Expression, error) {
+	// Logical combinators like $or, $and and $not
+	if strings.HasPrefix(fieldName, operatorPrefix) {
+		return d.buildLogicalExpression(db, schemaInfo, fieldName, givenFilter)
+	}
+
+	switch givenFilter := givenFilter.(type) {
+	// NULL, like {"deleted_by": nil} or {"group": nil} for records without a related group
+	case nil:
+		if fieldInfo, ok := relationalTypesInfo[fieldName]; ok {
+			return d.buildRelationNullExpression(db, schemaInfo, fieldInfo, false)
+		}
+
+		if _, ok := schemaInfo.FieldsByDBName[fieldName]; !ok {
+			return nil, newFilterError(schemaInfo, fieldName, givenFilter, ErrFieldDoesNotExist)
+		}
+
+		return clause.Eq{Column: clause.Column{Table: schemaInfo.Table, Name: fieldName}, Value: nil}, nil
+
+	// WithFilters for relational objects
+	case map[string]any:
+		fieldInfo, ok := relationalTypesInfo[fieldName]
+
+		// Operators on simple fields, like {"age": {"$gt": 18}}
+		if field, isColumn := schemaInfo.FieldsByDBName[fieldName]; !ok && isColumn && isOperatorMap(givenFilter) {
+			return buildOperatorExpression(schemaInfo, field, givenFilter)
+		}
+
+		// Paths in JSON columns, like {"metadata": {"labels": {"env": "prod"}}}
+		if field, isColumn := schemaInfo.FieldsByDBName[fieldName]; !ok && isColumn && isJSONColumn(field) {
+			return buildJSONExpression(db, schemaInfo, field, nil, givenFilter)
+		}
+
+		if !ok {
+			// Relations that gorm parsed but that can't be used, like {"pets": {...}} with a typo in its foreignKey
+			if relationship := getRelationship(db.NamingStrategy, schemaInfo, fieldName); relationship != nil {
+				_, err := getNestedType(relationship)
+				return nil, newFilterError(schemaInfo, fieldName, givenFilter, fmt.Errorf("%w: %w", err, ErrInvalidRelation))
+			}
+
+			// Embedded structs like {"address": {"city": "Utrecht"}}, these are columns of our own table
+			embedded, isEmbedded := d.getEmbeddedFieldsOfType(db.NamingStrategy, schemaInfo)[fieldName]
+			if !isEmbedded {
+				return nil, newFilterError(schemaInfo, fieldName, givenFilter, ErrFieldDoesNotExist)
+			}
+
+			return buildEmbeddedExpression(db, schemaInfo, fieldName, embedded, givenFilter)
+		}
+
+		return d.buildRelationExpression(db, schemaInfo, fieldName, fieldInfo, givenFilter)
+
+	// Simple filters (string, int, bool etc.), GORM can handle these
+	default:
+		field, ok := schemaInfo.FieldsByDBName[fieldName]
+		if !ok {
+			return nil, newFilterError(schemaInfo, fieldName, givenFilter, ErrFieldDoesNotExist)
+		}
+
+		value, err := coerceValue(field, givenFilter)
+		if err != nil {
+			return nil, newFilterError(schemaInfo, fieldName, givenFilter, err)
+		}
+
+		simpleFilter := map[string]any{schemaInfo.Table + "." + fieldName: value}
+		return clause.And(db.Statement.BuildCondition(simpleFilter)...), nil
+	}
 }
 
 // nestedType Wrapper object used to create subqueries.
-//
-// NOTICE: We can only do simple many-to-many's with 2 ids right now, I currently (15-06-2021) see no reason
-// to add even more advanced options.
 type nestedType struct {
 	// An empty instance of the object, used in db.Model(...)
 	fieldStructInstance any
-	fieldForeignKey     string
+	fieldForeignKeys    []string
+
+	// The table of the related type
+	fieldTable string
+
+	// The primary keys of the related table
+	fieldPrimaryKeys []string
 
-	// Whether this is a manyToOne, oneToMany or manyToMany. oneToOne is taken care of automatically.
+	// The columns of the related table that fieldForeignKeys refer to, only used in oneToMany and manyToMany
+	fieldReferenceKeys []string
+
+	// The columns of our own table that the foreign keys refer to, only used in manyToOne, oneToOne and manyToMany
+	referenceKeys []string
+
+	// Whether this is a manyToOne (has many), oneToMany (belongs to), oneToOne (has one) or manyToMany
 	relationType string
 
+	// The type column of the related table and our value in it, only used in polymorphic manyToOne and oneToOne
+	polymorphicType  string
+	polymorphicValue string
+
 	/////////////////////////
 	// Many to Many fields //
 	/////////////////////////
@@ -138,8 +282,19 @@ type nestedType struct {
 	// The name of the join table
 	manyToManyTable string
 
-	// The destination field from destinationManyToManyStructInstance
-	destinationManyToManyForeignKey string
+	// The columns of the join table that refer to our own table
+	destinationManyToManyForeignKeys []string
+}
+
+// sortedKeys returns the keys of the map in sorted order, so queries and errors are predictable
+func sortedKeys[T any](values map[string]T) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
 }
 
 // iKind is an abstraction of reflect.Value and reflect.Type that allows us to make ensureConcrete generic.
@@ -170,61 +325,72 @@ func ensureNotASlice(value reflect.Type) reflect.Type {
 	return result
 }
 
-// getInstanceAndRelationOfField Since db.Model(...) requires an instance, we use this function to instantiate a field type
-// and retrieve what kind of relation we assume the object has.
-func getInstanceAndRelationOfField(fieldType reflect.Type) (any, string) {
-	valueType := ensureConcrete(fieldType)
+// getNestedType Returns information about a relation in a nestedType object, based on the relationship
+// that gorm parsed. Used to figure out what database tables need to be queried.
+func getNestedType(relationship *schema.Relationship) (*nestedType, error) {
+	result := &nestedType{
+		// Get empty instance for db.Model() of the related type
+		fieldStructInstance: reflect.New(relationship.FieldSchema.ModelType).Interface(),
+		fieldTable:          relationship.FieldSchema.Table,
+		fieldPrimaryKeys:    relationship.FieldSchema.PrimaryFieldDBNames,
+	}
 
-	switch valueType.Kind() {
-	// If the given field is a struct, we can safely say it's a oneToMany, we instantiate it
-	// using reflect.New and return it as an object.
-	case reflect.Struct:
-		return reflect.New(valueType).Interface(), "oneToMany"
+	switch relationship.Type {
+	// The foreign key is in our own table
+	case schema.BelongsTo:
+		result.relationType = "oneToMany"
 
-	// If the given field is a slice, it can be either manyToOne or manyToMany. We figure out what
-	// kind of slice it is and use reflect.New to return it as an object
-	case reflect.Slice:
-		elementType := ensureNotASlice(valueType)
-		return reflect.New(elementType).Interface(), "manyToOne"
+	// The foreign key is in the related table
+	case schema.HasOne:
+		result.relationType = "oneToOne"
+	case schema.HasMany:
+		result.relationType = "manyToOne"
+
+	// The foreign keys are in the join table
+	case schema.Many2Many:
+		result.relationType = "manyToMany"
+		result.manyToManyTable = relationship.JoinTable.Table
 
 	default:
-		return nil, ""
+		return nil, fmt.Errorf("relation '%s' of field %s is unsupported", relationship.Type, relationship.Name)
 	}
-}
-
-// getNestedType Returns information about the struct field in a nestedType object. Used to figure out
-// what database tables need to be queried.
-func getNestedType(naming schema.Namer, dbField *schema.Field, ofType reflect.Type) (*nestedType, error) {
-	// Get empty instance for db.Model() of the given field
-	sourceStructType, relationType := getInstanceAndRelationOfField(dbField.FieldType)
 
-	result := &nestedType{
-		relationType:        relationType,
-		fieldStructInstance: sourceStructType,
+	// Polymorphic relations share the foreign key with other types, like comments.owner_id with comments.owner_type
+	if relationship.Polymorphic != nil {
+		result.polymorphicType = relationship.Polymorphic.PolymorphicType.DBName
+		result.polymorphicValue = relationship.Polymorphic.Value
 	}
 
-	sourceForeignKey, ok := dbField.TagSettings["FOREIGNKEY"]
-	if ok {
-		result.fieldForeignKey = naming.ColumnName(dbField.Schema.Table, sourceForeignKey)
-		return result, nil
-	}
+	// Composite keys have multiple references, they're kept in the same order on both sides
+	for _, reference := range relationship.References {
+		// Fixed values like polymorphic types don't reference a column
+		if reference.PrimaryKey == nil {
+			continue
+		}
 
-	// No foreign key found, then it must be a manyToMany
-	manyToMany, ok := dbField.TagSettings["MANY2MANY"]
+		// The 'references' of a relation, which is the primary key unless specified otherwise
+		if reference.OwnPrimaryKey {
+			result.referenceKeys = append(result.referenceKeys, reference.PrimaryKey.DBName)
+		} else {
+			result.fieldReferenceKeys = append(result.fieldReferenceKeys, reference.PrimaryKey.DBName)
+		}
 
-	if !ok {
-		return nil, fmt.Errorf("no 'foreignKey:...' or 'many2many:...' found in field %s", dbField.Name)
-	}
+		// In a many-to-many, one side of the join table refers to us and the other side to the related table
+		if relationship.Type == schema.Many2Many && reference.OwnPrimaryKey {
+			result.destinationManyToManyForeignKeys = append(result.destinationManyToManyForeignKeys, reference.ForeignKey.DBName)
+			continue
+		}
 
-	// Woah it's a many-to-many!
-	result.relationType = "manyToMany"
-	result.manyToManyTable = manyToMany
+		result.fieldForeignKeys = append(result.fieldForeignKeys, reference.ForeignKey.DBName)
+	}
 
-	// Based on the type we can just put _id behind it, again this only works with simple many-to-many structs
-	result.fieldForeignKey = naming.ColumnName(dbField.Schema.Table, ensureNotASlice(dbField.FieldType).Name()) + "_id"
+	if len(result.fieldForeignKeys) == 0 {
+		return nil, fmt.Errorf("no foreign key found for field %s", relationship.Name)
+	}
 
-	// Now the other table that we're getting information from.
-	result.destinationManyToManyForeignKey = naming.ColumnName(dbField.Schema.Table, ofType.Name()) + "_id"
+	if len(result.fieldPrimaryKeys) == 0 {
+		return nil, fmt.Errorf("no primary key found for the type of field %s", relationship.Name)
+	}
 
 	return result, nil
 }
@@ -247,86 +413,179 @@ func getNestedType(naming schema.Namer, dbField *schema.Field, ofType reflect.Ty
 // map of items.
 //
 //	{
-//		"nestedstruct": {
+//		"tag": {
 //			fieldStructInstance: Tag{},
-//			fieldForeignKey: "NestedStructRef",
+//			fieldForeignKeys: []string{"tag_ref"},
 //			relationType: "oneToMany"
 //		}
 //	}
-func getDatabaseFieldsOfType(naming schema.Namer, schemaInfo *schema.Schema) map[string]*nestedType {
+func (d *DeepGorm) getDatabaseFieldsOfType(naming schema.Namer, schemaInfo *schema.Schema) map[string]*nestedType {
 	// First get all the information of the to-be-reflected object
-	reflectType := ensureConcrete(schemaInfo.ModelType)
-	reflectTypeName := reflectType.Name()
+	key := cacheKey{modelType: ensureConcrete(schemaInfo.ModelType), table: schemaInfo.Table, naming: namingKey(naming)}
 
 	// The len(dbFields) check is needed here because when running the unit tests
 	// it fell into a race condition where it had the map key already stored but not the value yet.
 	// Resulting in some fields reported falsely as non existent
-	if dbFields, ok := cacheDatabaseMap.Load(reflectTypeName); ok && len(dbFields) != 0 {
+	if dbFields, ok := d.relationCache.Load(key); ok && len(dbFields) != 0 {
 		return dbFields
 	}
 
 	var resultNestedType = map[string]*nestedType{}
 
-	for _, fieldInfo := range schemaInfo.FieldsByName {
-		// Not interested in these
-		if kind := ensureConcrete(fieldInfo.FieldType).Kind(); kind != reflect.Struct && kind != reflect.Slice {
-			continue
-		}
-
-		nestedTypeResult, err := getNestedType(naming, fieldInfo, reflectType)
+	for _, relationship := range schemaInfo.Relationships.Relations {
+		nestedTypeResult, err := getNestedType(relationship)
 		if err != nil {
 			continue
 		}
 
-		resultNestedType[naming.ColumnName(schemaInfo.Table, fieldInfo.Name)] = nestedTypeResult
+		resultNestedType[naming.ColumnName(schemaInfo.Table, relationship.Name)] = nestedTypeResult
 	}
 
 	// Add to cache
-	cacheDatabaseMap.Store(reflectTypeName, resultNestedType)
+	d.relationCache.Store(key, resultNestedType)
 
 	return resultNestedType
 }
 
+// getRelationship returns the relationship that gorm parsed for the field, or nil if the field isn't a relation
+func getRelationship(naming schema.Namer, schemaInfo *schema.Schema, fieldName string) *schema.Relationship {
+	for _, relationship := range schemaInfo.Relationships.Relations {
+		if naming.ColumnName(schemaInfo.Table, relationship.Name) == fieldName {
+			return relationship
+		}
+	}
+
+	return nil
+}
+
 // AddDeepFilters / addDeepFilter godoc
-// Refer to AddDeepFilters.
-func addDeepFilter(db *gorm.DB, fieldInfo *nestedType, filter any) (*gorm.DB, error) {
+// Refer to AddDeepFilters. If complement is true, the related records that do NOT match the filter are used.
+//
+// Relations with a single key use IN-subqueries, composite keys use row values like (a, b) IN (SELECT x, y ...)
+// if the database supports them and correlated EXISTS-subqueries if it doesn't, refer to WithRowValueDialects.
+func (d *DeepGorm) addDeepFilter(db *gorm.DB, schemaInfo *schema.Schema, fieldInfo *nestedType, filter map[string]any, complement bool) (clause.Expression, error) {
 	cleanDB := db.Session(&gorm.Session{NewDB: true})
 
+	// We use a clean db object to create subqueries, reusing 'db' will cause a stackoverflow.
+	subQuery, err := d.buildDeepFilters(cleanDB, fieldInfo.fieldStructInstance, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// A filter without conditions like the one of {"pets": nil} returns the clean db, which shares the statement of
+	// 'db' until it's used. Its conditions would end up in the subquery, which includes the subquery itself.
+	if subQuery == cleanDB {
+		subQuery = cleanDB.Session(&gorm.Session{NewDB: true, Initialized: true})
+	}
+
+	// Using NOT IN instead of negating the filter itself, so related records with NULL values are part of the complement
+	if complement {
+		primaryKeys := columnsOf(fieldInfo.fieldTable, fieldInfo.fieldPrimaryKeys)
+
+		if d.useRowValues(db, fieldInfo.fieldPrimaryKeys) {
+			subQuery = cleanDB.Where(inSubquery(primaryKeys, cleanDB.Model(fieldInfo.fieldStructInstance).Select(fieldInfo.fieldPrimaryKeys).Where(subQuery), true))
+		} else {
+			// The filter refers to the table by its name, so it's about the outer record and not the aliased one
+			aliased := columnsOf(complementAlias, fieldInfo.fieldPrimaryKeys)
+			matching := cleanDB.Table("?", clause.Table{Name: fieldInfo.fieldTable, Alias: complementAlias}).Select("1").Where(columnsEqual(aliased, primaryKeys)).Where(subQuery)
+			subQuery = cleanDB.Where(existsSubquery(matching, true))
+		}
+	}
+
+	// Only the related records of our own type, after the complement so other types never end up in it
+	if fieldInfo.polymorphicType != "" {
+		subQuery = cleanDB.Where(polymorphicCondition(fieldInfo.fieldTable, fieldInfo)).Where(subQuery)
+	}
+
 	switch fieldInfo.relationType {
 	case "oneToMany":
-		// SELECT * FROM <table> WHERE fieldInfo.fieldForeignKey IN (SELECT id FROM fieldInfo.fieldStructInstance WHERE givenFilter)
-		whereQuery := fmt.Sprintf("%s IN (?)", fieldInfo.fieldForeignKey)
-		subQuery, err := AddDeepFilters(cleanDB, fieldInfo.fieldStructInstance, filter.(map[string]any))
+		foreignKeys := columnsOf(schemaInfo.Table, fieldInfo.fieldForeignKeys)
 
-		if err != nil {
-			return nil, err
+		if !d.useRowValues(db, fieldInfo.fieldForeignKeys) {
+			// SELECT * FROM <table> WHERE EXISTS (SELECT 1 FROM fieldInfo.fieldStructInstance WHERE <references> = <foreign keys> AND givenFilter)
+			related := correlatedRecords(cleanDB, schemaInfo, fieldInfo, fieldInfo.fieldReferenceKeys, foreignKeys, subQuery)
+
+			return relationExpression{positive: existsSubquery(related, false), negative: existsSubquery(related, true)}, nil
 		}
 
-		return db.Where(whereQuery, cleanDB.Model(fieldInfo.fieldStructInstance).Select("id").Where(subQuery)), nil
+		// SELECT * FROM <table> WHERE fieldInfo.fieldForeignKeys IN (SELECT fieldInfo.fieldReferenceKeys FROM fieldInfo.fieldStructInstance WHERE givenFilter)
+		related := cleanDB.Model(fieldInfo.fieldStructInstance).Select(fieldInfo.fieldReferenceKeys).Where(subQuery)
 
-	case "manyToOne":
-		// SELECT * FROM <table> WHERE id IN (SELECT fieldInfo.fieldStructInstance FROM fieldInfo.fieldStructInstance WHERE filter)
-		subQuery, err := AddDeepFilters(cleanDB, fieldInfo.fieldStructInstance, filter.(map[string]any))
+		return relationExpression{
+			positive: inSubquery(foreignKeys, related, false),
+			// A NULL foreign key is never related, so it must be part of the negated result
+			negative: or(anyNull(foreignKeys), inSubquery(foreignKeys, related, true)),
+		}, nil
 
-		if err != nil {
-			return nil, err
+	case "manyToOne", "oneToOne":
+		referenceKeys := columnsOf(schemaInfo.Table, fieldInfo.referenceKeys)
+
+		if !d.useRowValues(db, fieldInfo.referenceKeys) {
+			// SELECT * FROM <table> WHERE EXISTS (SELECT 1 FROM fieldInfo.fieldStructInstance WHERE <foreign keys> = <references> AND filter)
+			related := correlatedRecords(cleanDB, schemaInfo, fieldInfo, fieldInfo.fieldForeignKeys, referenceKeys, subQuery)
+
+			return relationExpression{positive: existsSubquery(related, false), negative: existsSubquery(related, true)}, nil
 		}
 
-		return db.Where("id IN (?)", cleanDB.Model(fieldInfo.fieldStructInstance).Select(fieldInfo.fieldForeignKey).Where(subQuery)), nil
+		// SELECT * FROM <table> WHERE fieldInfo.referenceKeys IN (SELECT fieldInfo.fieldForeignKeys FROM fieldInfo.fieldStructInstance WHERE filter)
+		related := cleanDB.Model(fieldInfo.fieldStructInstance).Select(fieldInfo.fieldForeignKeys).Where(subQuery)
+
+		// A single NULL in a NOT IN-subquery makes the whole condition NULL, so we leave those out
+		relatedNotNull := cleanDB.Model(fieldInfo.fieldStructInstance).Select(fieldInfo.fieldForeignKeys).Where(noneNull(columnsOf(fieldInfo.fieldTable, fieldInfo.fieldForeignKeys))).Where(subQuery)
+
+		return relationExpression{
+			positive: inSubquery(referenceKeys, related, false),
+			negative: inSubquery(referenceKeys, relatedNotNull, true),
+		}, nil
 
 	case "manyToMany":
-		// SELECT * FROM <table> WHERE id IN (SELECT <table>_id FROM fieldInfo.fieldForeignKey WHERE <other_table>_id IN (SELECT id FROM <other_table> WHERE givenFilter))
+		referenceKeys := columnsOf(schemaInfo.Table, fieldInfo.referenceKeys)
+		joinForeignKeys := columnsOf(fieldInfo.manyToManyTable, fieldInfo.fieldForeignKeys)
+		destinationKeys := columnsOf(fieldInfo.manyToManyTable, fieldInfo.destinationManyToManyForeignKeys)
 
-		// The one that connects the objects
-		subWhere := fmt.Sprintf("%s IN (?)", fieldInfo.fieldForeignKey)
-		subQuery, err := AddDeepFilters(cleanDB, fieldInfo.fieldStructInstance, filter.(map[string]any))
+		if !d.useRowValues(db, fieldInfo.referenceKeys) || !d.useRowValues(db, fieldInfo.fieldForeignKeys) {
+			// SELECT * FROM <table> WHERE EXISTS (SELECT 1 FROM fieldInfo.manyToManyTable WHERE <destination keys> = <references>
+			//   AND EXISTS (SELECT 1 FROM <other_table> WHERE <other references> = <join foreign keys> AND givenFilter))
+			related := cleanDB.Model(fieldInfo.fieldStructInstance).Select("1").Where(columnsEqual(columnsOf(fieldInfo.fieldTable, fieldInfo.fieldReferenceKeys), joinForeignKeys)).Where(subQuery)
+			joined := cleanDB.Table(fieldInfo.manyToManyTable).Select("1").Where(columnsEqual(destinationKeys, referenceKeys)).Where(existsSubquery(related, false))
 
-		if err != nil {
-			return nil, err
+			return relationExpression{positive: existsSubquery(joined, false), negative: existsSubquery(joined, true)}, nil
 		}
 
-		return db.Where("id IN (?)", cleanDB.Table(fieldInfo.manyToManyTable).Select(fieldInfo.destinationManyToManyForeignKey).Where(subWhere, cleanDB.Model(fieldInfo.fieldStructInstance).Select("id").Where(subQuery))), nil
+		// SELECT * FROM <table> WHERE fieldInfo.referenceKeys IN (SELECT <table>_id FROM fieldInfo.manyToManyTable WHERE <other_table>_id IN (SELECT fieldInfo.fieldReferenceKeys FROM <other_table> WHERE givenFilter))
+		related := cleanDB.Model(fieldInfo.fieldStructInstance).Select(fieldInfo.fieldReferenceKeys).Where(subQuery)
+		joined := cleanDB.Table(fieldInfo.manyToManyTable).Select(fieldInfo.destinationManyToManyForeignKeys).Where(inSubquery(joinForeignKeys, related, false))
+		joinedNotNull := cleanDB.Table(fieldInfo.manyToManyTable).Select(fieldInfo.destinationManyToManyForeignKeys).Where(noneNull(destinationKeys)).Where(inSubquery(joinForeignKeys, related, false))
+
+		return relationExpression{
+			positive: inSubquery(referenceKeys, joined, false),
+			negative: inSubquery(referenceKeys, joinedNotNull, true),
+		}, nil
 	}
 
 	return nil, fmt.Errorf("relationType '%s' unknown", fieldInfo.relationType)
 }
+
+// polymorphicCondition creates the condition that limits the related records in the given table to our own type,
+// for example: comments.owner_type = 'posts'
+func polymorphicCondition(table string, fieldInfo *nestedType) clause.Expression {
+	return clause.Eq{Column: clause.Column{Table: table, Name: fieldInfo.polymorphicType}, Value: fieldInfo.polymorphicValue}
+}
+
+// Compile-time interface check
+var _ clause.NegationExpressionBuilder = relationExpression{}
+
+// relationExpression is a subquery condition on a relation that knows its own negation. Simply putting NOT in front
+// of 'fk IN (...)' would drop all the rows that have a NULL foreign key, while those are definitely not related.
+type relationExpression struct {
+	positive clause.Expression
+	negative clause.Expression
+}
+
+func (r relationExpression) Build(builder clause.Builder) {
+	r.positive.Build(builder)
+}
+
+func (r relationExpression) NegationBuild(builder clause.Builder) {
+	r.negative.Build(builder)
+}
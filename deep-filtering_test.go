@@ -89,6 +89,133 @@ type ComplexStruct3 struct {
 	Tags []*Tag `gorm:"foreignKey:ComplexStructRef"`
 }
 
+type NullableGroup struct {
+	ID   uuid.UUID
+	Name string
+}
+
+type NullableMember struct {
+	ID              uuid.UUID
+	Name            string
+	NullableGroupID *uuid.UUID
+	NullableGroup   *NullableGroup `gorm:"foreignKey:NullableGroupID"`
+}
+
+type NullableChild struct {
+	ID               uuid.UUID
+	Name             string
+	NullableParentID *uuid.UUID
+}
+
+type NullableParent struct {
+	ID       uuid.UUID
+	Name     string
+	Children []*NullableChild `gorm:"foreignKey:NullableParentID"`
+}
+
+type ConventionCompany struct {
+	ID   uuid.UUID
+	Name string
+}
+
+type ConventionPassport struct {
+	ID               uuid.UUID
+	Country          string
+	ConventionUserID uuid.UUID
+}
+
+type ConventionPet struct {
+	ID               uuid.UUID
+	Name             string
+	ConventionUserID uuid.UUID
+}
+
+type ConventionUser struct {
+	ID        uuid.UUID
+	Name      string
+	CompanyID uuid.UUID
+	Company   *ConventionCompany
+	Passport  *ConventionPassport
+	Pets      []*ConventionPet
+}
+
+type KeyedCompany struct {
+	Code string `gorm:"primaryKey"`
+	Name string
+}
+
+type KeyedLanguage struct {
+	IsoCode string `gorm:"primaryKey"`
+	Name    string
+}
+
+type KeyedPet struct {
+	PetUUID   uuid.UUID `gorm:"primaryKey"`
+	Name      string
+	OwnerUUID uuid.UUID
+}
+
+type KeyedUser struct {
+	UserUUID    uuid.UUID `gorm:"primaryKey"`
+	Name        string
+	CompanyCode string
+	Company     *KeyedCompany    `gorm:"foreignKey:CompanyCode;references:Code"`
+	Pets        []*KeyedPet      `gorm:"foreignKey:OwnerUUID;references:UserUUID"`
+	Languages   []*KeyedLanguage `gorm:"many2many:keyed_user_languages"`
+}
+
+type JoinMember struct {
+	ID     uuid.UUID
+	Handle string `gorm:"unique"`
+	Name   string
+}
+
+type JoinTeam struct {
+	ID      uuid.UUID
+	Code    string `gorm:"unique"`
+	Name    string
+	Members []*JoinMember `gorm:"many2many:join_team_members;joinForeignKey:TeamUUID;joinReferences:MemberUUID"`
+	Leads   []*JoinMember `gorm:"many2many:join_team_leads;foreignKey:Code;joinForeignKey:TeamCode;references:Handle;joinReferences:LeadHandle"`
+}
+
+type SelfUser struct {
+	ID        uuid.UUID
+	Name      string
+	ManagerID *uuid.UUID
+	Manager   *SelfUser
+	Reports   []*SelfUser `gorm:"foreignKey:ManagerID"`
+	Friends   []*SelfUser `gorm:"many2many:self_user_friends"`
+}
+
+type PolyComment struct {
+	ID        uuid.UUID
+	Body      string
+	Score     int
+	OwnerID   uuid.UUID
+	OwnerType string
+}
+
+type PolyCover struct {
+	ID        uuid.UUID
+	Color     string
+	OwnerID   uuid.UUID
+	OwnerType string
+}
+
+type PolyPost struct {
+	ID       uuid.UUID
+	Title    string
+	Comments []*PolyComment `gorm:"polymorphic:Owner"`
+	Cover    *PolyCover     `gorm:"polymorphic:Owner"`
+}
+
+type PolyVideo struct {
+	ID       uuid.UUID
+	Title    string
+	Comments []*PolyComment `gorm:"polymorphic:Owner;polymorphicValue:clip"`
+	Cover    *PolyCover     `gorm:"polymorphic:Owner;polymorphicValue:clip"`
+}
+
 // Tests
 
 func TestGetDatabaseFieldsOfType_DoesNotReturnSimpleTypes(t *testing.T) {
@@ -107,7 +234,7 @@ func TestGetDatabaseFieldsOfType_DoesNotReturnSimpleTypes(t *testing.T) {
 	schemaInfo, _ := schema.Parse(&SimpleStruct1{}, &sync.Map{}, naming)
 
 	// Act
-	result := getDatabaseFieldsOfType(nil, schemaInfo)
+	result := New().getDatabaseFieldsOfType(nil, schemaInfo)
 
 	// Assert
 	assert.Equal(t, expectedResult, result)
@@ -134,7 +261,7 @@ func TestGetDatabaseFieldsOfType_ReturnsStructTypeFields(t *testing.T) {
 	schemaInfo, _ := schema.Parse(TypeWithStruct1{}, &sync.Map{}, naming)
 
 	// Act
-	result := getDatabaseFieldsOfType(naming, schemaInfo)
+	result := New().getDatabaseFieldsOfType(naming, schemaInfo)
 
 	// Assert
 	assert.Len(t, result, 1)
@@ -143,7 +270,7 @@ func TestGetDatabaseFieldsOfType_ReturnsStructTypeFields(t *testing.T) {
 	if assert.NotNil(t, result["nested_struct"]) {
 		// Check if it's a SimpleStruct1
 		assert.IsType(t, &SimpleStruct2{}, result["nested_struct"].fieldStructInstance)
-		assert.Equal(t, "nested_struct_ref", result["nested_struct"].fieldForeignKey)
+		assert.Equal(t, []string{"nested_struct_ref"}, result["nested_struct"].fieldForeignKeys)
 		assert.Equal(t, "oneToMany", result["nested_struct"].relationType)
 	}
 }
@@ -169,7 +296,7 @@ func TestGetDatabaseFieldsOfType_ReturnsStructTypeOfSliceFields(t *testing.T) {
 	schemaInfo, _ := schema.Parse(&TypeWithStruct2{}, &sync.Map{}, naming)
 
 	// Act
-	result := getDatabaseFieldsOfType(naming, schemaInfo)
+	result := New().getDatabaseFieldsOfType(naming, schemaInfo)
 
 	// Assert
 	assert.Len(t, result, 1)
@@ -178,7 +305,7 @@ func TestGetDatabaseFieldsOfType_ReturnsStructTypeOfSliceFields(t *testing.T) {
 	if assert.NotNil(t, result["nested_struct"]) {
 		// Check if it's a SimpleStruct1
 		assert.IsType(t, &SimpleStruct3{}, result["nested_struct"].fieldStructInstance)
-		assert.Equal(t, "type_with_struct_ref", result["nested_struct"].fieldForeignKey)
+		assert.Equal(t, []string{"type_with_struct_ref"}, result["nested_struct"].fieldForeignKeys)
 		assert.Equal(t, "manyToOne", result["nested_struct"].relationType)
 	}
 }
@@ -188,6 +315,7 @@ func TestGetDatabaseFieldsOfType_ReturnsStructTypeFieldsOnConsecutiveCalls(t *te
 	t.Cleanup(cleanupCache)
 	// Arrange
 	type SimpleStruct4 struct {
+		ID         int
 		Name       string
 		Occupation string
 	}
@@ -200,10 +328,11 @@ func TestGetDatabaseFieldsOfType_ReturnsStructTypeFieldsOnConsecutiveCalls(t *te
 	naming := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name())).NamingStrategy
 	schemaInfo, _ := schema.Parse(&TypeWithStruct3{}, &sync.Map{}, naming)
 
-	_ = getDatabaseFieldsOfType(naming, schemaInfo)
+	plugin := New()
+	_ = plugin.getDatabaseFieldsOfType(naming, schemaInfo)
 
 	// Act
-	result := getDatabaseFieldsOfType(naming, schemaInfo)
+	result := plugin.getDatabaseFieldsOfType(naming, schemaInfo)
 
 	// Assert
 	assert.Len(t, result, 1)
@@ -211,7 +340,7 @@ func TestGetDatabaseFieldsOfType_ReturnsStructTypeFieldsOnConsecutiveCalls(t *te
 	if assert.NotNil(t, result["nested_struct"]) {
 		assert.IsType(t, &SimpleStruct4{}, result["nested_struct"].fieldStructInstance)
 
-		assert.Equal(t, "nested_struct_ref", result["nested_struct"].fieldForeignKey)
+		assert.Equal(t, []string{"nested_struct_ref"}, result["nested_struct"].fieldForeignKeys)
 		assert.Equal(t, "oneToMany", result["nested_struct"].relationType)
 	}
 }
@@ -371,50 +500,6 @@ func TestEnsureNotASlice_ReturnsExpectedTypeOnDeepSliceAndPointers(t *testing.T)
 	assert.Equal(t, expectedReflect, result)
 }
 
-func TestGetInstanceAndValueTypeInfoOfField_ReturnsExpectedStructOnStruct(t *testing.T) {
-	t.Parallel()
-	t.Cleanup(cleanupCache)
-	// Arrange
-	type TestStruct struct{}
-	input := reflect.TypeOf(TestStruct{})
-
-	// Act
-	result, relation := getInstanceAndRelationOfField(input)
-
-	// Assert
-	assert.Equal(t, &TestStruct{}, result)
-	assert.Equal(t, "oneToMany", relation)
-}
-
-func TestGetInstanceAndValueTypeInfoOfField_ReturnsExpectedStructOnSlice(t *testing.T) {
-	t.Parallel()
-	t.Cleanup(cleanupCache)
-	// Arrange
-	type TestStruct struct{}
-	input := reflect.TypeOf([]TestStruct{})
-
-	// Act
-	result, relation := getInstanceAndRelationOfField(input)
-
-	// Assert
-	assert.Equal(t, &TestStruct{}, result)
-	assert.Equal(t, "manyToOne", relation)
-}
-
-func TestGetInstanceAndValueTypeInfoOfField_ReturnsNilOnNonStructUnknownType(t *testing.T) {
-	t.Parallel()
-	t.Cleanup(cleanupCache)
-	// Arrange
-	input := reflect.TypeOf(0)
-
-	// Act
-	result, relation := getInstanceAndRelationOfField(input)
-
-	// Assert
-	assert.Equal(t, nil, result)
-	assert.Equal(t, "", relation)
-}
-
 func TestGetNestedType_ReturnsExpectedTypeInfoOnOneToMany(t *testing.T) {
 	t.Parallel()
 	t.Cleanup(cleanupCache)
@@ -430,6 +515,10 @@ func TestGetNestedType_ReturnsExpectedTypeInfoOnOneToMany(t *testing.T) {
 
 		TestBID int
 		B       *NestedStruct1 `gorm:"foreignKey:TestBID"`
+
+		// Gorm's conventions, no tags needed
+		CID int
+		C   NestedStruct1
 	}
 
 	tests := map[string]struct {
@@ -447,6 +536,11 @@ func TestGetNestedType_ReturnsExpectedTypeInfoOnOneToMany(t *testing.T) {
 			field:              "B",
 			expectedForeignKey: "test_b_id",
 		},
+		"without tags": {
+			expected:           &NestedStruct1{},
+			field:              "C",
+			expectedForeignKey: "c_id",
+		},
 	}
 
 	for name, testData := range tests {
@@ -456,20 +550,20 @@ func TestGetNestedType_ReturnsExpectedTypeInfoOnOneToMany(t *testing.T) {
 			// Arrange
 			naming := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name())).NamingStrategy
 			schemaInfo, _ := schema.Parse(TestStruct{}, &sync.Map{}, naming)
-			field := schemaInfo.FieldsByName[testData.field]
+			relationship := schemaInfo.Relationships.Relations[testData.field]
 
 			// Act
-			result, err := getNestedType(naming, field, reflect.TypeOf(TestStruct{}))
+			result, err := getNestedType(relationship)
 
 			// Assert
 			assert.Nil(t, err)
 
 			if assert.NotNil(t, result) {
 				assert.Equal(t, "oneToMany", result.relationType)
-				assert.Equal(t, testData.expectedForeignKey, result.fieldForeignKey)
+				assert.Equal(t, []string{testData.expectedForeignKey}, result.fieldForeignKeys)
 				assert.Equal(t, testData.expected, result.fieldStructInstance)
 
-				assert.Equal(t, "", result.destinationManyToManyForeignKey)
+				assert.Empty(t, result.destinationManyToManyForeignKeys)
 				assert.Equal(t, "", result.manyToManyTable)
 			}
 		})
@@ -480,8 +574,9 @@ func TestGetNestedType_ReturnsExpectedTypeInfoOnManyToOne(t *testing.T) {
 	t.Parallel()
 	t.Cleanup(cleanupCache)
 	type NestedStruct2 struct {
-		ID  int
-		BID int
+		ID           int
+		BID          int
+		TestStructID int
 	}
 
 	type TestStruct struct {
@@ -490,26 +585,31 @@ func TestGetNestedType_ReturnsExpectedTypeInfoOnManyToOne(t *testing.T) {
 		AID int
 		A   []TestStruct    `gorm:"foreignKey:AID"`
 		B   []NestedStruct2 `gorm:"foreignKey:BID"`
+
+		// Gorm's conventions, no tags needed
+		C []*NestedStruct2
 	}
 
 	tests := map[string]struct {
-		inputType          reflect.Type
-		field              reflect.StructField
+		field              string
 		expectedForeignKey string
 		expected           any
 	}{
 		"first": {
 			expected:           &TestStruct{},
-			inputType:          reflect.TypeOf(TestStruct{}),
-			field:              reflect.TypeOf(TestStruct{}).Field(2),
+			field:              "A",
 			expectedForeignKey: "a_id",
 		},
 		"second": {
 			expected:           &NestedStruct2{},
-			inputType:          reflect.TypeOf(TestStruct{}),
-			field:              reflect.TypeOf(TestStruct{}).Field(3),
+			field:              "B",
 			expectedForeignKey: "b_id",
 		},
+		"without tags": {
+			expected:           &NestedStruct2{},
+			field:              "C",
+			expectedForeignKey: "test_struct_id",
+		},
 	}
 
 	for name, testData := range tests {
@@ -519,26 +619,61 @@ func TestGetNestedType_ReturnsExpectedTypeInfoOnManyToOne(t *testing.T) {
 			// Arrange
 			naming := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name())).NamingStrategy
 			schemaInfo, _ := schema.Parse(TestStruct{}, &sync.Map{}, naming)
-			field := schemaInfo.FieldsByName[testData.field.Name]
+			relationship := schemaInfo.Relationships.Relations[testData.field]
 
 			// Act
-			result, err := getNestedType(naming, field, nil)
+			result, err := getNestedType(relationship)
 
 			// Assert
 			assert.Nil(t, err)
 
 			if assert.NotNil(t, result) {
 				assert.Equal(t, "manyToOne", result.relationType)
-				assert.Equal(t, testData.expectedForeignKey, result.fieldForeignKey)
+				assert.Equal(t, []string{testData.expectedForeignKey}, result.fieldForeignKeys)
 				assert.Equal(t, testData.expected, result.fieldStructInstance)
 
-				assert.Equal(t, "", result.destinationManyToManyForeignKey)
+				assert.Empty(t, result.destinationManyToManyForeignKeys)
 				assert.Equal(t, "", result.manyToManyTable)
 			}
 		})
 	}
 }
 
+func TestGetNestedType_ReturnsExpectedTypeInfoOnOneToOne(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	// Arrange
+	type Passport struct {
+		ID       int
+		PersonID int
+	}
+
+	type Person struct {
+		ID       int
+		Passport *Passport
+	}
+
+	naming := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name())).NamingStrategy
+	schemaInfo, _ := schema.Parse(Person{}, &sync.Map{}, naming)
+	relationship := schemaInfo.Relationships.Relations["Passport"]
+
+	expected := &nestedType{
+		fieldStructInstance: &Passport{},
+		fieldTable:          "passports",
+		fieldForeignKeys:    []string{"person_id"},
+		fieldPrimaryKeys:    []string{"id"},
+		referenceKeys:       []string{"id"},
+		relationType:        "oneToOne",
+	}
+
+	// Act
+	result, err := getNestedType(relationship)
+
+	// Assert
+	assert.Nil(t, err)
+	assert.EqualValues(t, expected, result)
+}
+
 func TestGetNestedType_ReturnsExpectedTypeInfoOnManyToMany(t *testing.T) {
 	t.Parallel()
 	t.Cleanup(cleanupCache)
@@ -546,21 +681,23 @@ func TestGetNestedType_ReturnsExpectedTypeInfoOnManyToMany(t *testing.T) {
 	naming := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name())).NamingStrategy
 
 	schemaInfo, _ := schema.Parse(ManyA{}, &sync.Map{}, naming)
-	field := schemaInfo.FieldsByName["ManyBs"]
-
-	inputType := reflect.TypeOf(ManyA{})
+	relationship := schemaInfo.Relationships.Relations["ManyBs"]
 
 	// This is what ManyA should return
 	expected := &nestedType{
-		fieldStructInstance:             &ManyB{},
-		fieldForeignKey:                 "many_b_id",
-		relationType:                    "manyToMany",
-		manyToManyTable:                 "a_b",
-		destinationManyToManyForeignKey: "many_a_id",
+		fieldStructInstance:              &ManyB{},
+		fieldTable:                       "many_bs",
+		fieldForeignKeys:                 []string{"many_b_id"},
+		fieldPrimaryKeys:                 []string{"id"},
+		fieldReferenceKeys:               []string{"id"},
+		referenceKeys:                    []string{"id"},
+		relationType:                     "manyToMany",
+		manyToManyTable:                  "a_b",
+		destinationManyToManyForeignKeys: []string{"many_a_id"},
 	}
 
 	// Act
-	result, err := getNestedType(naming, field, inputType)
+	result, err := getNestedType(relationship)
 
 	// Assert
 	assert.Nil(t, err)
@@ -570,24 +707,66 @@ func TestGetNestedType_ReturnsExpectedTypeInfoOnManyToMany(t *testing.T) {
 	}
 }
 
-func TestGetNestedType_ReturnsErrorOnNoForeignKeys(t *testing.T) {
+func TestGetNestedType_ReturnsExpectedTypeInfoOnCustomKeys(t *testing.T) {
 	t.Parallel()
 	t.Cleanup(cleanupCache)
-	type NestedStruct3 struct{}
+	type Country struct {
+		Code string `gorm:"primaryKey"`
+		Name string `gorm:"unique"`
+	}
 
-	type TestStruct struct {
-		A *[]TestStruct `gorm:""`
-		B *[]NestedStruct3
+	type Account struct {
+		AccountUUID string `gorm:"primaryKey"`
+		Number      int    `gorm:"unique"`
+
+		CountryName string
+		Country     Country `gorm:"foreignKey:CountryName;references:Name"`
+
+		Children []*Account `gorm:"foreignKey:ParentNumber;references:Number"`
+
+		ParentNumber int
+		Countries    []*Country `gorm:"many2many:account_countries"`
 	}
 
 	tests := map[string]struct {
-		field string
+		field    string
+		expected *nestedType
 	}{
-		"first": {
-			field: "A",
+		"belongs to": {
+			field: "Country",
+			expected: &nestedType{
+				fieldStructInstance: &Country{},
+				fieldTable:          "countries",
+				fieldForeignKeys:    []string{"country_name"},
+				fieldPrimaryKeys:    []string{"code"},
+				fieldReferenceKeys:  []string{"name"},
+				relationType:        "oneToMany",
+			},
 		},
-		"second": {
-			field: "B",
+		"has many": {
+			field: "Children",
+			expected: &nestedType{
+				fieldStructInstance: &Account{},
+				fieldTable:          "accounts",
+				fieldForeignKeys:    []string{"parent_number"},
+				fieldPrimaryKeys:    []string{"account_uuid"},
+				referenceKeys:       []string{"number"},
+				relationType:        "manyToOne",
+			},
+		},
+		"many to many": {
+			field: "Countries",
+			expected: &nestedType{
+				fieldStructInstance:              &Country{},
+				fieldTable:                       "countries",
+				fieldForeignKeys:                 []string{"country_code"},
+				fieldPrimaryKeys:                 []string{"code"},
+				fieldReferenceKeys:               []string{"code"},
+				referenceKeys:                    []string{"account_uuid"},
+				relationType:                     "manyToMany",
+				manyToManyTable:                  "account_countries",
+				destinationManyToManyForeignKeys: []string{"account_account_uuid"},
+			},
 		},
 	}
 
@@ -597,19 +776,192 @@ func TestGetNestedType_ReturnsErrorOnNoForeignKeys(t *testing.T) {
 			t.Parallel()
 			// Arrange
 			naming := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name())).NamingStrategy
-			schemaInfo, _ := schema.Parse(TestStruct{}, &sync.Map{}, naming)
-			field := schemaInfo.FieldsByName[testData.field]
+			schemaInfo, _ := schema.Parse(Account{}, &sync.Map{}, naming)
+			relationship := schemaInfo.Relationships.Relations[testData.field]
 
 			// Act
-			result, err := getNestedType(naming, field, nil)
+			result, err := getNestedType(relationship)
 
 			// Assert
-			assert.Nil(t, result)
+			assert.Nil(t, err)
+			assert.EqualValues(t, testData.expected, result)
+		})
+	}
+}
 
-			if assert.NotNil(t, err) {
-				expected := fmt.Sprintf("no 'foreignKey:...' or 'many2many:...' found in field %v", testData.field)
-				assert.Equal(t, expected, err.Error())
-			}
+func TestGetNestedType_ReturnsExpectedTypeInfoOnRenamedJoinColumns(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	tests := map[string]struct {
+		field    string
+		expected *nestedType
+	}{
+		"join columns": {
+			field: "Members",
+			expected: &nestedType{
+				fieldStructInstance:              &JoinMember{},
+				fieldTable:                       "join_members",
+				fieldForeignKeys:                 []string{"member_uuid"},
+				fieldPrimaryKeys:                 []string{"id"},
+				fieldReferenceKeys:               []string{"id"},
+				referenceKeys:                    []string{"id"},
+				relationType:                     "manyToMany",
+				manyToManyTable:                  "join_team_members",
+				destinationManyToManyForeignKeys: []string{"team_uuid"},
+			},
+		},
+		"join columns with references": {
+			field: "Leads",
+			expected: &nestedType{
+				fieldStructInstance:              &JoinMember{},
+				fieldTable:                       "join_members",
+				fieldForeignKeys:                 []string{"lead_handle"},
+				fieldPrimaryKeys:                 []string{"id"},
+				fieldReferenceKeys:               []string{"handle"},
+				referenceKeys:                    []string{"code"},
+				relationType:                     "manyToMany",
+				manyToManyTable:                  "join_team_leads",
+				destinationManyToManyForeignKeys: []string{"team_code"},
+			},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			naming := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name())).NamingStrategy
+			schemaInfo, _ := schema.Parse(JoinTeam{}, &sync.Map{}, naming)
+			relationship := schemaInfo.Relationships.Relations[testData.field]
+
+			// Act
+			result, err := getNestedType(relationship)
+
+			// Assert
+			assert.Nil(t, err)
+			assert.EqualValues(t, testData.expected, result)
+		})
+	}
+}
+
+func TestGetNestedType_ReturnsExpectedTypeInfoOnSelfReference(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	tests := map[string]struct {
+		field    string
+		expected *nestedType
+	}{
+		"belongs to": {
+			field: "Manager",
+			expected: &nestedType{
+				fieldStructInstance: &SelfUser{},
+				fieldTable:          "self_users",
+				fieldForeignKeys:    []string{"manager_id"},
+				fieldPrimaryKeys:    []string{"id"},
+				fieldReferenceKeys:  []string{"id"},
+				relationType:        "oneToMany",
+			},
+		},
+		"has many": {
+			field: "Reports",
+			expected: &nestedType{
+				fieldStructInstance: &SelfUser{},
+				fieldTable:          "self_users",
+				fieldForeignKeys:    []string{"manager_id"},
+				fieldPrimaryKeys:    []string{"id"},
+				referenceKeys:       []string{"id"},
+				relationType:        "manyToOne",
+			},
+		},
+		"many to many": {
+			field: "Friends",
+			expected: &nestedType{
+				fieldStructInstance:              &SelfUser{},
+				fieldTable:                       "self_users",
+				fieldForeignKeys:                 []string{"friend_id"},
+				fieldPrimaryKeys:                 []string{"id"},
+				fieldReferenceKeys:               []string{"id"},
+				referenceKeys:                    []string{"id"},
+				relationType:                     "manyToMany",
+				manyToManyTable:                  "self_user_friends",
+				destinationManyToManyForeignKeys: []string{"self_user_id"},
+			},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			naming := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name())).NamingStrategy
+			schemaInfo, _ := schema.Parse(SelfUser{}, &sync.Map{}, naming)
+			relationship := schemaInfo.Relationships.Relations[testData.field]
+
+			// Act
+			result, err := getNestedType(relationship)
+
+			// Assert
+			assert.Nil(t, err)
+			assert.EqualValues(t, testData.expected, result)
+		})
+	}
+}
+
+func TestGetNestedType_ReturnsExpectedTypeInfoOnPolymorphic(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	tests := map[string]struct {
+		objectType any
+		field      string
+		expected   *nestedType
+	}{
+		"has many": {
+			objectType: PolyPost{},
+			field:      "Comments",
+			expected: &nestedType{
+				fieldStructInstance: &PolyComment{},
+				fieldTable:          "poly_comments",
+				fieldForeignKeys:    []string{"owner_id"},
+				fieldPrimaryKeys:    []string{"id"},
+				referenceKeys:       []string{"id"},
+				relationType:        "manyToOne",
+				polymorphicType:     "owner_type",
+				polymorphicValue:    "poly_posts",
+			},
+		},
+		"has one with value": {
+			objectType: PolyVideo{},
+			field:      "Cover",
+			expected: &nestedType{
+				fieldStructInstance: &PolyCover{},
+				fieldTable:          "poly_covers",
+				fieldForeignKeys:    []string{"owner_id"},
+				fieldPrimaryKeys:    []string{"id"},
+				referenceKeys:       []string{"id"},
+				relationType:        "oneToOne",
+				polymorphicType:     "owner_type",
+				polymorphicValue:    "clip",
+			},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			naming := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name())).NamingStrategy
+			schemaInfo, _ := schema.Parse(testData.objectType, &sync.Map{}, naming)
+			relationship := schemaInfo.Relationships.Relations[testData.field]
+
+			// Act
+			result, err := getNestedType(relationship)
+
+			// Assert
+			assert.Nil(t, err)
+			assert.EqualValues(t, testData.expected, result)
 		})
 	}
 }
@@ -2873,7 +3225,969 @@ func TestAddDeepFilters_AddsDeepFiltersWithManyToMany2OnMultiFilter(t *testing.T
 	}
 }
 
+func TestAddDeepFilters_AddsOperatorFilters(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	records := []*ComplexStruct1{
+		{
+			ID:        uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+			Value:     1,
+			NestedRef: uuid.MustParse("71766db4-eb17-4457-a85c-8b89af5a319d"),
+			Nested: &NestedStruct4{
+				ID:         uuid.MustParse("71766db4-eb17-4457-a85c-8b89af5a319d"),
+				Name:       "Johan",
+				Occupation: "Dev",
+			},
+		},
+		{
+			ID:        uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+			Value:     11,
+			NestedRef: uuid.MustParse("4604bb79-ee05-4a09-b874-c3af8964d8c4"),
+			Nested: &NestedStruct4{
+				ID:         uuid.MustParse("4604bb79-ee05-4a09-b874-c3af8964d8c4"),
+				Name:       "Katherina",
+				Occupation: "Dev",
+			},
+		},
+		{
+			ID:        uuid.MustParse("c98dc9f2-bfa5-4ab5-9cbb-76800e09e512"),
+			Value:     21,
+			NestedRef: uuid.MustParse("4604bb79-ee05-4a09-b874-c3af8964d8c5"),
+			Nested: &NestedStruct4{
+				ID:         uuid.MustParse("4604bb79-ee05-4a09-b874-c3af8964d8c5"),
+				Name:       "Derek",
+				Occupation: "Ops",
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		filterMap   map[string]any
+		expectedIDs []uuid.UUID
+	}{
+		"greater than": {
+			filterMap: map[string]any{
+				"value": map[string]any{"$gt": 1},
+			},
+			expectedIDs: []uuid.UUID{
+				uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+				uuid.MustParse("c98dc9f2-bfa5-4ab5-9cbb-76800e09e512"),
+			},
+		},
+		"range": {
+			filterMap: map[string]any{
+				"value": map[string]any{"$gte": 11, "$lte": 20},
+			},
+			expectedIDs: []uuid.UUID{
+				uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+			},
+		},
+		"between": {
+			filterMap: map[string]any{
+				"value": map[string]any{"$between": []int{0, 11}},
+			},
+			expectedIDs: []uuid.UUID{
+				uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+				uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+			},
+		},
+		"nested not equal": {
+			filterMap: map[string]any{
+				"nested": map[string]any{
+					"occupation": map[string]any{"$ne": "Dev"},
+				},
+			},
+			expectedIDs: []uuid.UUID{
+				uuid.MustParse("c98dc9f2-bfa5-4ab5-9cbb-76800e09e512"),
+			},
+		},
+		"nested like and simple filter": {
+			filterMap: map[string]any{
+				"value": map[string]any{"$lt": 20},
+				"nested": map[string]any{
+					"name":       map[string]any{"$like": "%a%"},
+					"occupation": "Dev",
+				},
+			},
+			expectedIDs: []uuid.UUID{
+				uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+				uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+			},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&ComplexStruct1{}, &NestedStruct4{})
+
+			database.CreateInBatches(records, len(records))
+
+			// Act
+			query, err := AddDeepFilters(database, ComplexStruct1{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []uuid.UUID
+				res := query.Model(&ComplexStruct1{}).Order("value").Pluck("id", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, testData.expectedIDs, result)
+			}
+		})
+	}
+}
+
+func TestAddDeepFilters_ReturnsErrorOnInvalidOperators(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedError error
+		expectedMsg   string
+	}{
+		"unknown operator": {
+			filterMap: map[string]any{
+				"value": map[string]any{"$near": 5},
+			},
+			expectedError: ErrUnsupportedOperator,
+			expectedMsg:   "failed to add filters for 'complex_struct1.value': '$near': unsupported operator",
+		},
+		"nested operator on wrong type": {
+			filterMap: map[string]any{
+				"nested": map[string]any{
+					"name": map[string]any{"$between": []string{"a"}},
+				},
+			},
+			expectedError: ErrInvalidOperatorValue,
+			expectedMsg:   "failed to add filters for 'nested_struct4.name': '$between' expects a list of 2 values, got 1: invalid operator value",
+		},
+		"operator on unknown field": {
+			filterMap: map[string]any{
+				"unknown": map[string]any{"$gt": 5},
+			},
+			expectedError: ErrFieldDoesNotExist,
+			expectedMsg:   "failed to add filters for 'complex_struct1.unknown': field does not exist",
+		},
+		"mixed operators and fields": {
+			filterMap: map[string]any{
+				"value": map[string]any{"$gt": 5, "name": "abc"},
+			},
+			expectedError: ErrFieldDoesNotExist,
+			expectedMsg:   "failed to add filters for 'complex_struct1.value': field does not exist",
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+
+			// Act
+			query, err := AddDeepFilters(database, ComplexStruct1{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, query)
+			assert.ErrorIs(t, err, testData.expectedError)
+			assert.EqualError(t, err, testData.expectedMsg)
+		})
+	}
+}
+
+func TestAddDeepFilters_AddsNegatedDeepFiltersWithOneToMany(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	records := []*NullableMember{
+		{
+			ID:   uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+			Name: "admin",
+			NullableGroup: &NullableGroup{
+				ID:   uuid.MustParse("71766db4-eb17-4457-a85c-8b89af5a319d"),
+				Name: "admin",
+			},
+		},
+		{
+			ID:   uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+			Name: "user",
+			NullableGroup: &NullableGroup{
+				ID:   uuid.MustParse("4604bb79-ee05-4a09-b874-c3af8964d8c4"),
+				Name: "user",
+			},
+		},
+		{
+			ID:   uuid.MustParse("c98dc9f2-bfa5-4ab5-9cbb-76800e09e512"),
+			Name: "without group",
+		},
+	}
+
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedNames []string
+	}{
+		"not related": {
+			filterMap: map[string]any{
+				"$not": map[string]any{
+					"nullable_group": map[string]any{"name": "admin"},
+				},
+			},
+			expectedNames: []string{"user", "without group"},
+		},
+		"not related or named": {
+			filterMap: map[string]any{
+				"$not": map[string]any{
+					"$or": []map[string]any{
+						{"nullable_group": map[string]any{"name": "admin"}},
+						{"name": "user"},
+					},
+				},
+			},
+			expectedNames: []string{"without group"},
+		},
+		"double negation": {
+			filterMap: map[string]any{
+				"$not": map[string]any{
+					"$not": map[string]any{
+						"nullable_group": map[string]any{"name": "admin"},
+					},
+				},
+			},
+			expectedNames: []string{"admin"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&NullableMember{}, &NullableGroup{})
+
+			database.CreateInBatches(records, len(records))
+
+			// Act
+			query, err := AddDeepFilters(database, NullableMember{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []string
+				res := query.Model(&NullableMember{}).Order("name").Pluck("name", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, testData.expectedNames, result)
+			}
+		})
+	}
+}
+
+func TestAddDeepFilters_AddsNegatedDeepFiltersWithManyToOne(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedNames []string
+	}{
+		"not related": {
+			filterMap: map[string]any{
+				"$not": map[string]any{
+					"children": map[string]any{"name": "admin"},
+				},
+			},
+			expectedNames: []string{"empty", "users"},
+		},
+		"not related with simple filter": {
+			filterMap: map[string]any{
+				"$not": map[string]any{
+					"children": map[string]any{"name": "admin"},
+					"name":     "users",
+				},
+			},
+			expectedNames: []string{"admins", "empty", "users"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&NullableParent{}, &NullableChild{})
+
+			database.Create([]*NullableParent{
+				{
+					ID:   uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+					Name: "admins",
+					Children: []*NullableChild{
+						{ID: uuid.MustParse("71766db4-eb17-4457-a85c-8b89af5a319d"), Name: "admin"},
+					},
+				},
+				{
+					ID:   uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+					Name: "users",
+					Children: []*NullableChild{
+						{ID: uuid.MustParse("4604bb79-ee05-4a09-b874-c3af8964d8c4"), Name: "user"},
+					},
+				},
+				{
+					ID:   uuid.MustParse("c98dc9f2-bfa5-4ab5-9cbb-76800e09e512"),
+					Name: "empty",
+				},
+			})
+
+			// An orphan that matches the filter, its NULL foreign key should not influence the result
+			database.Create(&NullableChild{ID: uuid.MustParse("2ad6a4fe-e0a4-4791-8f10-df6317cdb8b5"), Name: "admin"})
+
+			// Act
+			query, err := AddDeepFilters(database, NullableParent{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []string
+				res := query.Model(&NullableParent{}).Order("name").Pluck("name", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, testData.expectedNames, result)
+			}
+		})
+	}
+}
+
+func TestAddDeepFilters_AddsNegatedDeepFiltersWithManyToMany(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	records := []*ManyA{
+		{
+			ID: uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+			A:  "hello",
+			ManyBs: []*ManyB{
+				{ID: uuid.MustParse("9f1baf72-6ca5-4d43-8a01-d845575620e1"), B: "world"},
+			},
+		},
+		{
+			ID: uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+			A:  "goodbye",
+			ManyBs: []*ManyB{
+				{ID: uuid.MustParse("4604bb79-ee05-4a09-b874-c3af8964d8c4"), B: "moon"},
+			},
+		},
+		{
+			ID: uuid.MustParse("c98dc9f2-bfa5-4ab5-9cbb-76800e09e512"),
+			A:  "alone",
+		},
+	}
+
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedNames []string
+	}{
+		"not related": {
+			filterMap: map[string]any{
+				"$not": map[string]any{
+					"many_bs": map[string]any{"b": "world"},
+				},
+			},
+			expectedNames: []string{"alone", "goodbye"},
+		},
+		"not related to either": {
+			filterMap: map[string]any{
+				"$not": map[string]any{
+					"many_bs": map[string]any{"b": []string{"world", "moon"}},
+				},
+			},
+			expectedNames: []string{"alone"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&ManyA{}, &ManyB{})
+
+			database.CreateInBatches(records, len(records))
+
+			// Act
+			query, err := AddDeepFilters(database, ManyA{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []string
+				res := query.Model(&ManyA{}).Order("a").Pluck("a", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, testData.expectedNames, result)
+			}
+		})
+	}
+}
+
+func TestAddDeepFilters_AddsDeepFiltersWithGormConventions(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	records := []*ConventionUser{
+		{
+			ID:       uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+			Name:     "Jessica",
+			Company:  &ConventionCompany{ID: uuid.MustParse("1c83a7c9-e95d-4dba-b858-5eb4e34ebcf2"), Name: "Acme"},
+			Passport: &ConventionPassport{ID: uuid.MustParse("17983ba8-2d26-4e36-bb6b-6c5a04b6606e"), Country: "NL"},
+			Pets: []*ConventionPet{
+				{ID: uuid.MustParse("451d635a-83f2-47da-b12c-50ec49e45509"), Name: "Fluffy"},
+			},
+		},
+		{
+			ID:       uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+			Name:     "Amy",
+			Company:  &ConventionCompany{ID: uuid.MustParse("8977cd8b-ebb8-4119-93d5-cbe605d8f668"), Name: "Globex"},
+			Passport: &ConventionPassport{ID: uuid.MustParse("8927cd8b-ebb8-4119-93d5-cbe605d8f668"), Country: "BE"},
+			Pets: []*ConventionPet{
+				{ID: uuid.MustParse("411ed385-c1ca-432d-b577-6d6138450264"), Name: "Rex"},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedNames []string
+	}{
+		"belongs to": {
+			filterMap: map[string]any{
+				"company": map[string]any{"name": "Acme"},
+			},
+			expectedNames: []string{"Jessica"},
+		},
+		"has one": {
+			filterMap: map[string]any{
+				"passport": map[string]any{"country": "BE"},
+			},
+			expectedNames: []string{"Amy"},
+		},
+		"has many": {
+			filterMap: map[string]any{
+				"pets": map[string]any{"name": "Fluffy"},
+			},
+			expectedNames: []string{"Jessica"},
+		},
+		"not has one": {
+			filterMap: map[string]any{
+				"$not": map[string]any{
+					"passport": map[string]any{"country": "BE"},
+				},
+			},
+			expectedNames: []string{"Jessica"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&ConventionCompany{}, &ConventionUser{}, &ConventionPassport{}, &ConventionPet{})
+
+			database.CreateInBatches(records, len(records))
+
+			// Act
+			query, err := AddDeepFilters(database, ConventionUser{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []string
+				res := query.Model(&ConventionUser{}).Order("name").Pluck("name", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, testData.expectedNames, result)
+			}
+		})
+	}
+}
+
+func TestAddDeepFilters_AddsDeepFiltersWithCustomPrimaryKeys(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	dutch := &KeyedLanguage{IsoCode: "nl", Name: "Dutch"}
+	english := &KeyedLanguage{IsoCode: "en", Name: "English"}
+
+	records := []*KeyedUser{
+		{
+			UserUUID:  uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+			Name:      "Jessica",
+			Company:   &KeyedCompany{Code: "ACME", Name: "Acme"},
+			Languages: []*KeyedLanguage{dutch, english},
+			Pets: []*KeyedPet{
+				{PetUUID: uuid.MustParse("451d635a-83f2-47da-b12c-50ec49e45509"), Name: "Fluffy"},
+				{PetUUID: uuid.MustParse("8977cd8b-ebb8-4119-93d5-cbe605d8f668"), Name: "Rex"},
+			},
+		},
+		{
+			UserUUID:  uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+			Name:      "Amy",
+			Company:   &KeyedCompany{Code: "GLOBEX", Name: "Globex"},
+			Languages: []*KeyedLanguage{english},
+			Pets: []*KeyedPet{
+				{PetUUID: uuid.MustParse("411ed385-c1ca-432d-b577-6d6138450264"), Name: "Rex"},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedNames []string
+	}{
+		"belongs to": {
+			filterMap: map[string]any{
+				"company": map[string]any{"name": "Acme"},
+			},
+			expectedNames: []string{"Jessica"},
+		},
+		"has many": {
+			filterMap: map[string]any{
+				"pets": map[string]any{"name": "Fluffy"},
+			},
+			expectedNames: []string{"Jessica"},
+		},
+		"has many all": {
+			filterMap: map[string]any{
+				"pets": map[string]any{
+					"$all": map[string]any{"name": "Rex"},
+				},
+			},
+			expectedNames: []string{"Amy"},
+		},
+		"has many count": {
+			filterMap: map[string]any{
+				"pets": map[string]any{"$count": 2},
+			},
+			expectedNames: []string{"Jessica"},
+		},
+		"many to many": {
+			filterMap: map[string]any{
+				"languages": map[string]any{"name": "Dutch"},
+			},
+			expectedNames: []string{"Jessica"},
+		},
+		"many to many count": {
+			filterMap: map[string]any{
+				"languages": map[string]any{"$count": 1},
+			},
+			expectedNames: []string{"Amy"},
+		},
+		"not many to many": {
+			filterMap: map[string]any{
+				"$not": map[string]any{
+					"languages": map[string]any{"name": "Dutch"},
+				},
+			},
+			expectedNames: []string{"Amy"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&KeyedCompany{}, &KeyedLanguage{}, &KeyedUser{}, &KeyedPet{})
+
+			database.CreateInBatches(records, len(records))
+
+			// Act
+			query, err := AddDeepFilters(database, KeyedUser{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []string
+				res := query.Model(&KeyedUser{}).Order("name").Pluck("name", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, testData.expectedNames, result)
+			}
+		})
+	}
+}
+
+func TestAddDeepFilters_AddsDeepFiltersWithRenamedJoinColumns(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	jessica := &JoinMember{ID: uuid.MustParse("1c83a7c9-e95d-4dba-b858-5eb4e34ebcf2"), Handle: "jess", Name: "Jessica"}
+	amy := &JoinMember{ID: uuid.MustParse("17983ba8-2d26-4e36-bb6b-6c5a04b6606e"), Handle: "amy", Name: "Amy"}
+
+	records := []*JoinTeam{
+		{
+			ID:      uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+			Code:    "DEV",
+			Name:    "Developers",
+			Members: []*JoinMember{jessica, amy},
+			Leads:   []*JoinMember{jessica},
+		},
+		{
+			ID:      uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+			Code:    "OPS",
+			Name:    "Operations",
+			Members: []*JoinMember{amy},
+			Leads:   []*JoinMember{amy},
+		},
+	}
+
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedNames []string
+	}{
+		"join columns": {
+			filterMap: map[string]any{
+				"members": map[string]any{"name": "Jessica"},
+			},
+			expectedNames: []string{"Developers"},
+		},
+		"join columns count": {
+			filterMap: map[string]any{
+				"members": map[string]any{"$count": 1},
+			},
+			expectedNames: []string{"Operations"},
+		},
+		"join columns with references": {
+			filterMap: map[string]any{
+				"leads": map[string]any{"name": "Amy"},
+			},
+			expectedNames: []string{"Operations"},
+		},
+		"not join columns with references": {
+			filterMap: map[string]any{
+				"$not": map[string]any{
+					"leads": map[string]any{"name": "Amy"},
+				},
+			},
+			expectedNames: []string{"Developers"},
+		},
+		"join columns with references max": {
+			filterMap: map[string]any{
+				"leads": map[string]any{
+					"$max": map[string]any{"name": "Jessica"},
+				},
+			},
+			expectedNames: []string{"Developers"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&JoinMember{}, &JoinTeam{})
+
+			database.CreateInBatches(records, len(records))
+
+			// Act
+			query, err := AddDeepFilters(database, JoinTeam{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []string
+				res := query.Model(&JoinTeam{}).Order("name").Pluck("name", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, testData.expectedNames, result)
+			}
+		})
+	}
+}
+
+func TestAddDeepFilters_AddsDeepFiltersWithSelfReferences(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	aliceID := uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687")
+	bobID := uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69")
+	carolID := uuid.MustParse("c98dc9f2-bfa5-4ab5-9cbb-76800e09e512")
+
+	// Alice manages Bob, who manages Carol. Alice is friends with Bob, who is friends with Carol
+	records := []*SelfUser{
+		{ID: aliceID, Name: "Alice"},
+		{ID: bobID, Name: "Bob", ManagerID: &aliceID},
+		{ID: carolID, Name: "Carol", ManagerID: &bobID},
+	}
+
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedNames []string
+	}{
+		"belongs to": {
+			filterMap: map[string]any{
+				"manager": map[string]any{"name": "Alice"},
+			},
+			expectedNames: []string{"Bob"},
+		},
+		"not belongs to": {
+			filterMap: map[string]any{
+				"$not": map[string]any{
+					"manager": map[string]any{"name": "Alice"},
+				},
+			},
+			expectedNames: []string{"Alice", "Carol"},
+		},
+		"belongs to twice": {
+			filterMap: map[string]any{
+				"manager": map[string]any{
+					"manager": map[string]any{"name": "Alice"},
+				},
+			},
+			expectedNames: []string{"Carol"},
+		},
+		"has many": {
+			filterMap: map[string]any{
+				"reports": map[string]any{"name": "Carol"},
+			},
+			expectedNames: []string{"Bob"},
+		},
+		"has many count": {
+			filterMap: map[string]any{
+				"reports": map[string]any{"$count": 0},
+			},
+			expectedNames: []string{"Carol"},
+		},
+		"many to many": {
+			filterMap: map[string]any{
+				"friends": map[string]any{"name": "Carol"},
+			},
+			expectedNames: []string{"Bob"},
+		},
+		"many to many none": {
+			filterMap: map[string]any{
+				"friends": map[string]any{"$none": map[string]any{}},
+			},
+			expectedNames: []string{"Carol"},
+		},
+		"many to many twice": {
+			filterMap: map[string]any{
+				"friends": map[string]any{
+					"friends": map[string]any{"name": "Carol"},
+				},
+			},
+			expectedNames: []string{"Alice"},
+		},
+		"many to many min": {
+			filterMap: map[string]any{
+				"friends": map[string]any{
+					"$min": map[string]any{"name": "Bob"},
+				},
+			},
+			expectedNames: []string{"Alice"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&SelfUser{})
+
+			database.CreateInBatches(records, len(records))
+			_ = database.Model(records[0]).Association("Friends").Append(records[1])
+			_ = database.Model(records[1]).Association("Friends").Append(records[2])
+
+			// Act
+			query, err := AddDeepFilters(database, SelfUser{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []string
+				res := query.Model(&SelfUser{}).Order("name").Pluck("name", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, testData.expectedNames, result)
+			}
+		})
+	}
+}
+
+func TestAddDeepFilters_AddsDeepFiltersWithPolymorphic(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	// The post and the video share ids, so only the type tells their comments apart
+	firstID := uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687")
+	secondID := uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69")
+
+	posts := []*PolyPost{
+		{
+			ID:    firstID,
+			Title: "first post",
+			Comments: []*PolyComment{
+				{ID: uuid.MustParse("1c83a7c9-e95d-4dba-b858-5eb4e34ebcf2"), Body: "nice", Score: 1},
+			},
+			Cover: &PolyCover{ID: uuid.MustParse("17983ba8-2d26-4e36-bb6b-6c5a04b6606e"), Color: "red"},
+		},
+		{
+			ID:    secondID,
+			Title: "second post",
+		},
+	}
+
+	videos := []*PolyVideo{
+		{
+			ID:    firstID,
+			Title: "first video",
+			Comments: []*PolyComment{
+				{ID: uuid.MustParse("451d635a-83f2-47da-b12c-50ec49e45509"), Body: "great", Score: 5},
+				{ID: uuid.MustParse("8977cd8b-ebb8-4119-93d5-cbe605d8f668"), Body: "nice", Score: 3},
+			},
+			Cover: &PolyCover{ID: uuid.MustParse("8927cd8b-ebb8-4119-93d5-cbe605d8f668"), Color: "blue"},
+		},
+		{
+			ID:    secondID,
+			Title: "second video",
+			Comments: []*PolyComment{
+				{ID: uuid.MustParse("411ed385-c1ca-432d-b577-6d6138450264"), Body: "great", Score: 2},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		objectType     any
+		filterMap      map[string]any
+		expectedTitles []string
+	}{
+		"has many": {
+			objectType: PolyPost{},
+			filterMap: map[string]any{
+				"comments": map[string]any{"body": "great"},
+			},
+			expectedTitles: []string{},
+		},
+		"has many with value": {
+			objectType: PolyVideo{},
+			filterMap: map[string]any{
+				"comments": map[string]any{"body": "great"},
+			},
+			expectedTitles: []string{"first video", "second video"},
+		},
+		"has many all": {
+			objectType: PolyPost{},
+			filterMap: map[string]any{
+				"comments": map[string]any{
+					"$all": map[string]any{"body": "nice"},
+					"$any": map[string]any{},
+				},
+			},
+			expectedTitles: []string{"first post"},
+		},
+		"has many none": {
+			objectType: PolyPost{},
+			filterMap: map[string]any{
+				"comments": map[string]any{"$none": map[string]any{}},
+			},
+			expectedTitles: []string{"second post"},
+		},
+		"has many count": {
+			objectType: PolyVideo{},
+			filterMap: map[string]any{
+				"comments": map[string]any{"$count": 2},
+			},
+			expectedTitles: []string{"first video"},
+		},
+		"has many sum": {
+			objectType: PolyPost{},
+			filterMap: map[string]any{
+				"comments": map[string]any{
+					"$sum": map[string]any{"score": 1},
+				},
+			},
+			expectedTitles: []string{"first post"},
+		},
+		"has one": {
+			objectType: PolyPost{},
+			filterMap: map[string]any{
+				"cover": map[string]any{"color": "blue"},
+			},
+			expectedTitles: []string{},
+		},
+		"not has one with value": {
+			objectType: PolyVideo{},
+			filterMap: map[string]any{
+				"$not": map[string]any{
+					"cover": map[string]any{"color": "red"},
+				},
+			},
+			expectedTitles: []string{"first video", "second video"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&PolyPost{}, &PolyVideo{}, &PolyComment{}, &PolyCover{})
+
+			database.CreateInBatches(posts, len(posts))
+			database.CreateInBatches(videos, len(videos))
+
+			// Act
+			query, err := AddDeepFilters(database, testData.objectType, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []string
+				res := query.Model(testData.objectType).Order("title").Pluck("title", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, testData.expectedTitles, result)
+			}
+		})
+	}
+}
+
 func cleanupCache() {
-	cacheDatabaseMap.Clear()
-	schemaCache.Clear()
+	defaultDeepGorm.relationCache.Clear()
+	defaultDeepGorm.schemaCache.Clear()
+	defaultDeepGorm.keyCache.Clear()
+	defaultDeepGorm.embeddedCache.Clear()
 }
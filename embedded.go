@@ -0,0 +1,226 @@
+package deepgorm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// embeddedType is a struct embedded using gorm:"embedded", its columns are part of our own table
+type embeddedType struct {
+	// The field of the struct in the model or the struct it's embedded in
+	field reflect.StructField
+
+	// The columns of the struct by their name without prefix, like city => addr_city
+	columns map[string]*schema.Field
+
+	// The structs that are embedded in this one
+	embedded map[string]*embeddedType
+
+	// The names of the columns and embedded structs by the keys that may be used in filters, refer to WithKeys
+	keys map[string]string
+}
+
+// getEmbeddedFieldsOfType godoc
+// Returns the embedded structs of the type by their field name, for example:
+//
+//	type Address struct {
+//		City string
+//	}
+//
+//	type User struct {
+//		Address Address `gorm:"embedded;embeddedPrefix:addr_"`
+//	}
+//
+// Returns:
+//
+//	{
+//		"address": {
+//			columns: {"city": <addr_city>},
+//		},
+//	}
+//
+// Anonymous structs without the embedded tag are left out, gorm adds their columns to the table as if they were our own.
+func (d *DeepGorm) getEmbeddedFieldsOfType(naming schema.Namer, schemaInfo *schema.Schema) map[string]*embeddedType {
+	key := cacheKey{modelType: ensureConcrete(schemaInfo.ModelType), table: schemaInfo.Table, naming: namingKey(naming)}
+
+	if embeddedTypes, ok := d.embeddedCache.Load(key); ok {
+		return embeddedTypes
+	}
+
+	root := &embeddedType{embedded: map[string]*embeddedType{}}
+
+	for _, field := range schemaInfo.Fields {
+		// Relations and ignored fields don't have a column
+		if len(field.BindNames) < 2 || field.DBName == "" {
+			continue
+		}
+
+		current := root
+		columnName := field.DBName
+		structType := schemaInfo.ModelType
+
+		// Follow the path of structs to the field, the prefixes are added from the outside in
+		for _, name := range field.BindNames[:len(field.BindNames)-1] {
+			structField, _ := ensureConcrete(structType).FieldByName(name)
+			structType = structField.Type
+
+			tagSettings := schema.ParseTagSetting(structField.Tag.Get("gorm"), ";")
+			columnName = strings.TrimPrefix(columnName, tagSettings["EMBEDDEDPREFIX"])
+
+			if _, ok := tagSettings["EMBEDDED"]; !ok && structField.Anonymous {
+				continue
+			}
+
+			key := naming.ColumnName(schemaInfo.Table, name)
+			if _, ok := current.embedded[key]; !ok {
+				current.embedded[key] = &embeddedType{field: structField, columns: map[string]*schema.Field{}, embedded: map[string]*embeddedType{}}
+			}
+
+			current = current.embedded[key]
+		}
+
+		if current != root {
+			current.columns[columnName] = field
+		}
+	}
+
+	d.resolveEmbeddedKeys(root.embedded)
+
+	d.embeddedCache.Store(key, root.embedded)
+
+	return root.embedded
+}
+
+// resolveEmbeddedKeys sets the keys of the embedded structs and the structs embedded in them, refer to resolveKeys
+func (d *DeepGorm) resolveEmbeddedKeys(embeddedTypes map[string]*embeddedType) {
+	for _, embedded := range embeddedTypes {
+		embedded.keys = d.resolveKeys(keyedFieldsOf(embedded.columns, embedded.embedded))
+		d.resolveEmbeddedKeys(embedded.embedded)
+	}
+}
+
+// keyedFieldsOf returns the columns and embedded structs sorted by their name, so the same field takes precedence
+// every time in resolveKeys
+func keyedFieldsOf(columns map[string]*schema.Field, embeddedTypes map[string]*embeddedType) []keyedField {
+	result := make([]keyedField, 0, len(columns)+len(embeddedTypes))
+
+	for name, field := range columns {
+		result = append(result, keyedField{field: field.StructField, column: name})
+	}
+
+	for name, embedded := range embeddedTypes {
+		result = append(result, keyedField{field: embedded.field, column: name})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].column < result[j].column
+	})
+
+	return result
+}
+
+// isEmbeddedColumn returns whether the field is a column of an embedded struct, refer to getEmbeddedFieldsOfType
+func isEmbeddedColumn(schemaInfo *schema.Schema, field *schema.Field) bool {
+	structType := schemaInfo.ModelType
+
+	for _, name := range field.BindNames[:len(field.BindNames)-1] {
+		structField, _ := ensureConcrete(structType).FieldByName(name)
+		structType = structField.Type
+
+		if _, ok := schema.ParseTagSetting(structField.Tag.Get("gorm"), ";")["EMBEDDED"]; ok || !structField.Anonymous {
+			return true
+		}
+	}
+
+	return false
+}
+
+// buildEmbeddedExpression godoc
+// Turns a filter on an embedded struct into conditions on the columns of our own table, for example:
+//
+//	map[string]any{
+//		"address": map[string]any{
+//			"city": "Utrecht",
+//		},
+//	}
+//
+// Is turned into:
+//
+//	users.addr_city = 'Utrecht'
+//
+// The path is used in errors and contains the names of the embedded structs, like address.geo. Empty filter maps
+// aren't allowed, they don't contain any conditions.
+func buildEmbeddedExpression(db *gorm.DB, schemaInfo *schema.Schema, path string, embedded *embeddedType, filter map[string]any) (clause.Expression, error) {
+	if len(filter) == 0 {
+		return nil, newFilterError(schemaInfo, path, filter, fmt.Errorf("expected a non-empty filter map: %w", ErrInvalidOperatorValue))
+	}
+
+	keys := sortedKeys(filter)
+
+	expressions := make([]clause.Expression, 0, len(keys))
+
+	var errs []error
+
+	for _, key := range keys {
+		expression, err := buildEmbeddedFieldExpression(db, schemaInfo, path, embedded, key, filter[key])
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		expressions = append(expressions, expression)
+	}
+
+	if len(errs) > 0 {
+		return nil, joinErrors(errs)
+	}
+
+	return clause.And(expressions...), nil
+}
+
+// buildEmbeddedFieldExpression turns a single key of a filter on an embedded struct into an expression, refer to
+// buildEmbeddedExpression
+func buildEmbeddedFieldExpression(db *gorm.DB, schemaInfo *schema.Schema, path string, embedded *embeddedType, key string, value any) (clause.Expression, error) {
+	nestedFilter, isMap := value.(map[string]any)
+
+	// The key may be an alias, the path contains the key as it was given
+	name := embedded.keys[key]
+
+	if nested, ok := embedded.embedded[name]; ok && isMap {
+		return buildEmbeddedExpression(db, schemaInfo, path+"."+key, nested, nestedFilter)
+	}
+
+	field, ok := embedded.columns[name]
+	if !ok || (isMap && !isOperatorMap(nestedFilter)) {
+		return nil, newFilterError(schemaInfo, path+"."+key, value, ErrFieldDoesNotExist)
+	}
+
+	// Operators like {"zip": {"$like": "35%"}}
+	if isMap {
+		expression, err := buildOperatorExpression(schemaInfo, field, nestedFilter)
+
+		var filterError *FilterError
+		if errors.As(err, &filterError) {
+			filterError.Path = path + "." + key
+		}
+
+		return expression, err
+	}
+
+	coerced, err := coerceValue(field, value)
+	if err != nil {
+		filterError := newFilterError(schemaInfo, field.DBName, value, err)
+		filterError.Path = path + "." + key
+		return nil, filterError
+	}
+
+	simpleFilter := map[string]any{schemaInfo.Table + "." + field.DBName: coerced}
+	return clause.And(db.Statement.BuildCondition(simpleFilter)...), nil
+}
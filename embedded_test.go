@@ -0,0 +1,365 @@
+package deepgorm
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ing-bank/gormtestutil"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/schema"
+)
+
+type EmbeddedGeo struct {
+	Lat float64
+	Lng float64
+}
+
+type EmbeddedAddress struct {
+	City string
+	Zip  string      `gorm:"column:postal_code"`
+	Geo  EmbeddedGeo `gorm:"embedded;embeddedPrefix:geo_"`
+}
+
+type EmbeddedAudit struct {
+	CreatedBy string
+}
+
+type EmbeddedPerson struct {
+	EmbeddedAudit
+	ID             uuid.UUID
+	Name           string
+	EmbeddedTeamID uuid.UUID
+	Address        EmbeddedAddress `gorm:"embedded;embeddedPrefix:addr_"`
+	Billing        EmbeddedAddress `gorm:"embedded;embeddedPrefix:billing_"`
+}
+
+type EmbeddedTeam struct {
+	ID      uuid.UUID
+	Name    string
+	Members []*EmbeddedPerson
+}
+
+func TestGetEmbeddedFieldsOfType_ReturnsEmbeddedStructs(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	// Arrange
+	naming := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name())).NamingStrategy
+	schemaInfo, _ := schema.Parse(EmbeddedPerson{}, &sync.Map{}, naming)
+
+	// Act
+	result := New().getEmbeddedFieldsOfType(naming, schemaInfo)
+
+	// Assert
+	assert.Len(t, result, 2)
+
+	if assert.NotNil(t, result["address"]) {
+		assert.Len(t, result["address"].columns, 2)
+		assert.Equal(t, "addr_city", result["address"].columns["city"].DBName)
+		assert.Equal(t, "addr_postal_code", result["address"].columns["postal_code"].DBName)
+
+		if assert.NotNil(t, result["address"].embedded["geo"]) {
+			assert.Len(t, result["address"].embedded["geo"].columns, 2)
+			assert.Equal(t, "addr_geo_lat", result["address"].embedded["geo"].columns["lat"].DBName)
+			assert.Equal(t, "addr_geo_lng", result["address"].embedded["geo"].columns["lng"].DBName)
+		}
+	}
+
+	if assert.NotNil(t, result["billing"]) {
+		assert.Equal(t, "billing_city", result["billing"].columns["city"].DBName)
+	}
+}
+
+func TestGetEmbeddedFieldsOfType_CachesEmbeddedStructs(t *testing.T) {
+	t.Parallel()
+	// Arrange
+	naming := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name())).NamingStrategy
+	schemaInfo, _ := schema.Parse(EmbeddedPerson{}, &sync.Map{}, naming)
+	plugin := New()
+
+	first := plugin.getEmbeddedFieldsOfType(naming, schemaInfo)
+
+	// Act
+	result := plugin.getEmbeddedFieldsOfType(naming, schemaInfo)
+
+	// Assert
+	key := cacheKey{modelType: schemaInfo.ModelType, table: schemaInfo.Table, naming: namingKey(naming)}
+	cached, ok := plugin.embeddedCache.Load(key)
+
+	assert.True(t, ok)
+	assert.Same(t, first["address"], cached["address"])
+	assert.Same(t, first["address"], result["address"])
+}
+
+func TestAddDeepFilters_AddsEmbeddedFilters(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	records := []*EmbeddedPerson{
+		{
+			ID:            uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+			Name:          "Jessica",
+			EmbeddedAudit: EmbeddedAudit{CreatedBy: "admin"},
+			Address:       EmbeddedAddress{City: "Utrecht", Zip: "3511", Geo: EmbeddedGeo{Lat: 52.09, Lng: 5.12}},
+			Billing:       EmbeddedAddress{City: "Amsterdam", Zip: "1012"},
+		},
+		{
+			ID:            uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+			Name:          "Amy",
+			EmbeddedAudit: EmbeddedAudit{CreatedBy: "import"},
+			Address:       EmbeddedAddress{City: "Amsterdam", Zip: "1012", Geo: EmbeddedGeo{Lat: 52.37, Lng: 4.90}},
+			Billing:       EmbeddedAddress{City: "Amsterdam", Zip: "1013"},
+		},
+	}
+
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedNames []string
+	}{
+		"embedded": {
+			filterMap: map[string]any{
+				"address": map[string]any{"city": "Utrecht"},
+			},
+			expectedNames: []string{"Jessica"},
+		},
+		"same struct with a different prefix": {
+			filterMap: map[string]any{
+				"billing": map[string]any{"city": "Amsterdam"},
+			},
+			expectedNames: []string{"Amy", "Jessica"},
+		},
+		"custom column name with operator": {
+			filterMap: map[string]any{
+				"address": map[string]any{
+					"postal_code": map[string]any{"$like": "35%"},
+				},
+			},
+			expectedNames: []string{"Jessica"},
+		},
+		"nested embedded": {
+			filterMap: map[string]any{
+				"address": map[string]any{
+					"geo": map[string]any{
+						"lat": map[string]any{"$gt": 52.2},
+					},
+				},
+			},
+			expectedNames: []string{"Amy"},
+		},
+		"anonymous embedded": {
+			filterMap: map[string]any{
+				"created_by": "admin",
+			},
+			expectedNames: []string{"Jessica"},
+		},
+		"within logical combinator": {
+			filterMap: map[string]any{
+				"$not": map[string]any{
+					"address": map[string]any{"city": "Utrecht"},
+				},
+			},
+			expectedNames: []string{"Amy"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&EmbeddedPerson{})
+
+			database.CreateInBatches(records, len(records))
+
+			// Act
+			query, err := AddDeepFilters(database, EmbeddedPerson{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []string
+				res := query.Model(&EmbeddedPerson{}).Order("name").Pluck("name", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, testData.expectedNames, result)
+			}
+		})
+	}
+}
+
+func TestAddDeepFilters_AddsEmbeddedFiltersInRelations(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	// Arrange
+	records := []*EmbeddedTeam{
+		{
+			ID:   uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+			Name: "Utrecht office",
+			Members: []*EmbeddedPerson{
+				{ID: uuid.MustParse("1c83a7c9-e95d-4dba-b858-5eb4e34ebcf2"), Address: EmbeddedAddress{City: "Utrecht"}},
+			},
+		},
+		{
+			ID:   uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+			Name: "Amsterdam office",
+			Members: []*EmbeddedPerson{
+				{ID: uuid.MustParse("17983ba8-2d26-4e36-bb6b-6c5a04b6606e"), Address: EmbeddedAddress{City: "Amsterdam"}},
+			},
+		},
+	}
+
+	database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+	_ = database.AutoMigrate(&EmbeddedTeam{}, &EmbeddedPerson{})
+
+	database.CreateInBatches(records, len(records))
+
+	filter := map[string]any{
+		"members": map[string]any{
+			"address": map[string]any{"city": "Amsterdam"},
+		},
+	}
+
+	// Act
+	query, err := AddDeepFilters(database, EmbeddedTeam{}, filter)
+
+	// Assert
+	assert.Nil(t, err)
+
+	if assert.NotNil(t, query) {
+		var result []string
+		res := query.Model(&EmbeddedTeam{}).Pluck("name", &result)
+
+		// Handle error
+		assert.Nil(t, res.Error)
+
+		assert.Equal(t, []string{"Amsterdam office"}, result)
+	}
+}
+
+func TestAddDeepFilters_ReturnsErrorOnInvalidEmbeddedFilters(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedError error
+		expectedMsg   string
+	}{
+		"unknown column": {
+			filterMap: map[string]any{
+				"address": map[string]any{"street": "Main street"},
+			},
+			expectedError: ErrFieldDoesNotExist,
+			expectedMsg:   "failed to add filters for 'embedded_people.address.street': field does not exist",
+		},
+		"prefixed column": {
+			filterMap: map[string]any{
+				"address": map[string]any{"addr_city": "Utrecht"},
+			},
+			expectedError: ErrFieldDoesNotExist,
+			expectedMsg:   "failed to add filters for 'embedded_people.address.addr_city': field does not exist",
+		},
+		"unknown nested column": {
+			filterMap: map[string]any{
+				"address": map[string]any{
+					"geo": map[string]any{"altitude": 5},
+				},
+			},
+			expectedError: ErrFieldDoesNotExist,
+			expectedMsg:   "failed to add filters for 'embedded_people.address.geo.altitude': field does not exist",
+		},
+		"map on column": {
+			filterMap: map[string]any{
+				"address": map[string]any{
+					"city": map[string]any{"name": "Utrecht"},
+				},
+			},
+			expectedError: ErrFieldDoesNotExist,
+			expectedMsg:   "failed to add filters for 'embedded_people.address.city': field does not exist",
+		},
+		"unsupported operator": {
+			filterMap: map[string]any{
+				"address": map[string]any{
+					"geo": map[string]any{
+						"lat": map[string]any{"$like": "52%"},
+					},
+				},
+			},
+			expectedError: ErrUnsupportedOperator,
+			expectedMsg:   "failed to add filters for 'embedded_people.addr_geo_lat': '$like' can't be used on type 'float': unsupported operator",
+		},
+		"empty map": {
+			filterMap: map[string]any{
+				"address": map[string]any{},
+			},
+			expectedError: ErrInvalidOperatorValue,
+			expectedMsg:   "failed to add filters for 'embedded_people.address': expected a non-empty filter map: invalid operator value",
+		},
+		"empty nested map": {
+			filterMap: map[string]any{
+				"address": map[string]any{"geo": map[string]any{}},
+			},
+			expectedError: ErrInvalidOperatorValue,
+			expectedMsg:   "failed to add filters for 'embedded_people.address.geo': expected a non-empty filter map: invalid operator value",
+		},
+		"empty map in $not": {
+			filterMap: map[string]any{
+				"$not": map[string]any{"address": map[string]any{}},
+			},
+			expectedError: ErrInvalidOperatorValue,
+			expectedMsg:   "failed to add filters for 'embedded_people.address': expected a non-empty filter map: invalid operator value",
+		},
+		"empty map in $or": {
+			filterMap: map[string]any{
+				"$or": []map[string]any{{"address": map[string]any{}}, {"name": "a"}},
+			},
+			expectedError: ErrInvalidOperatorValue,
+			expectedMsg:   "failed to add filters for 'embedded_people.address': expected a non-empty filter map: invalid operator value",
+		},
+		"empty map in $and": {
+			filterMap: map[string]any{
+				"$and": []map[string]any{{"address": map[string]any{}}, {"name": "a"}},
+			},
+			expectedError: ErrInvalidOperatorValue,
+			expectedMsg:   "failed to add filters for 'embedded_people.address': expected a non-empty filter map: invalid operator value",
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+
+			// Act
+			query, err := AddDeepFilters(database, EmbeddedPerson{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, query)
+			assert.ErrorIs(t, err, testData.expectedError)
+			assert.EqualError(t, err, testData.expectedMsg)
+		})
+	}
+}
+
+func TestDeepGorm_Initialize_ReturnsErrorOnEmptyEmbeddedFilters(t *testing.T) {
+	t.Parallel()
+	// Arrange
+	database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+	_ = database.AutoMigrate(&EmbeddedPerson{})
+	_ = database.Use(New())
+
+	// Act
+	err := database.Where(map[string]any{"address": map[string]any{}}).Find(&[]EmbeddedPerson{}).Error
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidOperatorValue)
+
+	var filterError *FilterError
+	if assert.ErrorAs(t, err, &filterError) {
+		assert.Equal(t, "address", filterError.Path)
+	}
+}
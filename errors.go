@@ -0,0 +1,268 @@
+package deepgorm
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+// FilterErrorKind describes what is wrong with a filter, refer to FilterError
+type FilterErrorKind string
+
+const (
+	// KindUnknownField means the model doesn't have the field, wraps ErrFieldDoesNotExist
+	KindUnknownField FilterErrorKind = "unknown field"
+
+	// KindInvalidValue means the value has the wrong type or shape, wraps ErrInvalidOperatorValue
+	KindInvalidValue FilterErrorKind = "invalid value"
+
+	// KindUnsupportedOperator means the operator is unknown or can't be used here, wraps ErrUnsupportedOperator
+	KindUnsupportedOperator FilterErrorKind = "unsupported operator"
+
+	// KindTypeMismatch means the value can't be converted to the type of the field, wraps ErrTypeMismatch
+	KindTypeMismatch FilterErrorKind = "type mismatch"
+
+	// KindInvalidRelation means the field is a relation that can't be used in filters, wraps ErrInvalidRelation
+	KindInvalidRelation FilterErrorKind = "invalid relation"
+
+	// KindNotFilterable means the field may not be used in filters, wraps ErrFieldNotFilterable
+	KindNotFilterable FilterErrorKind = "field not filterable"
+)
+
+// FilterError godoc
+// Returned by AddDeepFilters if a filter can't be used, it can be retrieved using errors.As:
+//
+//	var filterError *deepgorm.FilterError
+//	if errors.As(err, &filterError) {
+//		// filterError.Path is 'group.owner.name', filterError.Model is 'Owner'
+//	}
+//
+// The sentinel errors like ErrFieldDoesNotExist can still be found using errors.Is.
+type FilterError struct {
+	// Kind is the type of problem
+	Kind FilterErrorKind
+
+	// Path is the path of keys in the filter map that leads to the problem, like group.owner.name. Elements of
+	// $and and $or are referred to by their index, like $or.1.name
+	Path string
+
+	// Model is the name of the struct the field was looked up in
+	Model string
+
+	// Value is the value that was given for the field
+	Value any
+
+	// Err describes the problem in more detail
+	Err error
+
+	// table and field are the location of the problem in the database, used in the message
+	table string
+	field string
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("failed to add filters for '%s.%s': %v", e.table, e.field, e.Err)
+}
+
+func (e *FilterError) Unwrap() error {
+	return e.Err
+}
+
+// newFilterError creates a FilterError for the field of the schema, the kind is derived from the sentinel error in err
+func newFilterError(schemaInfo *schema.Schema, field string, value any, err error) *FilterError {
+	return &FilterError{
+		Kind:  filterErrorKind(err),
+		Path:  field,
+		Model: schemaInfo.Name,
+		Value: value,
+		Err:   err,
+		table: schemaInfo.Table,
+		field: field,
+	}
+}
+
+// filterErrorKind returns the kind that belongs to the sentinel error in err
+func filterErrorKind(err error) FilterErrorKind {
+	switch {
+	case errors.Is(err, ErrFieldDoesNotExist):
+		return KindUnknownField
+	case errors.Is(err, ErrUnsupportedOperator):
+		return KindUnsupportedOperator
+	case errors.Is(err, ErrInvalidRelation):
+		return KindInvalidRelation
+	case errors.Is(err, ErrTypeMismatch):
+		return KindTypeMismatch
+	case errors.Is(err, ErrFieldNotFilterable):
+		return KindNotFilterable
+	}
+
+	return KindInvalidValue
+}
+
+// prependPath adds the keys to the front of the path of the FilterErrors in err, used when the error comes from a
+// nested filter map. Other errors are returned as they are.
+func prependPath(err error, keys ...string) error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, inner := range joined.Unwrap() {
+			prependPath(inner, keys...)
+		}
+
+		return err
+	}
+
+	var filterError *FilterError
+	if errors.As(err, &filterError) {
+		filterError.Path = strings.Join(keys, ".") + "." + filterError.Path
+	}
+
+	return err
+}
+
+// renamePath replaces the first key of the path of the FilterErrors in err if it's the column, used when the key in
+// the filter is an alias of the column, refer to WithKeys. Other errors are returned as they are.
+func renamePath(err error, column string, key string) error {
+	if column == key {
+		return err
+	}
+
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, inner := range joined.Unwrap() {
+			renamePath(inner, column, key)
+		}
+
+		return err
+	}
+
+	var filterError *FilterError
+	if errors.As(err, &filterError) {
+		if first, rest, found := strings.Cut(filterError.Path, "."); first == column && found {
+			filterError.Path = key + "." + rest
+		} else if first == column {
+			filterError.Path = key
+		}
+	}
+
+	return err
+}
+
+// joinErrors combines the errors using errors.Join, errors that were joined before are flattened so all of them
+// end up in a single list in the order they were found.
+func joinErrors(errs []error) error {
+	var result []error
+
+	for _, err := range errs {
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			result = append(result, joined.Unwrap()...)
+			continue
+		}
+
+		result = append(result, err)
+	}
+
+	return errors.Join(result...)
+}
+
+// mergeErrors combines the fields that may not be used from checkFilters with the problems from buildDeepFilters in
+// sorted-key order, refer to comparePaths. Problems below a field that may not be used are left out, the field itself
+// is reported already.
+func mergeErrors(policyErr error, buildErr error) error {
+	policyErrs := errorList(policyErr)
+
+	var denied []string
+	for _, err := range policyErrs {
+		denied = append(denied, pathOf(err))
+	}
+
+	var buildErrs []error
+
+	for _, err := range errorList(buildErr) {
+		path := pathOf(err)
+
+		if !slices.ContainsFunc(denied, func(deniedPath string) bool {
+			return path == deniedPath || strings.HasPrefix(path, deniedPath+".")
+		}) {
+			buildErrs = append(buildErrs, err)
+		}
+	}
+
+	result := make([]error, 0, len(policyErrs)+len(buildErrs))
+
+	// Both lists are in sorted-key order already
+	for len(policyErrs) > 0 && len(buildErrs) > 0 {
+		if comparePaths(pathOf(buildErrs[0]), pathOf(policyErrs[0])) < 0 {
+			result, buildErrs = append(result, buildErrs[0]), buildErrs[1:]
+		} else {
+			result, policyErrs = append(result, policyErrs[0]), policyErrs[1:]
+		}
+	}
+
+	result = append(append(result, policyErrs...), buildErrs...)
+
+	if len(result) == 0 {
+		return nil
+	}
+
+	return joinErrors(result)
+}
+
+// errorList returns the errors that were joined using joinErrors, or a list with the error itself
+func errorList(err error) []error {
+	if err == nil {
+		return nil
+	}
+
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+
+	return []error{err}
+}
+
+// pathOf returns the path of the FilterError in err, or an empty string for other errors
+func pathOf(err error) string {
+	var filterError *FilterError
+	if errors.As(err, &filterError) {
+		return filterError.Path
+	}
+
+	return ""
+}
+
+// comparePaths compares the paths key by key, the way filter maps are processed. Indexes of $and and $or are compared
+// as numbers, so $or.2 comes before $or.10.
+func comparePaths(a string, b string) int {
+	aKeys, bKeys := strings.Split(a, "."), strings.Split(b, ".")
+
+	for index := 0; index < len(aKeys) && index < len(bKeys); index++ {
+		aIndex, aErr := strconv.Atoi(aKeys[index])
+		bIndex, bErr := strconv.Atoi(bKeys[index])
+
+		if aErr == nil && bErr == nil {
+			if result := cmp.Compare(aIndex, bIndex); result != 0 {
+				return result
+			}
+
+			continue
+		}
+
+		if result := strings.Compare(aKeys[index], bKeys[index]); result != 0 {
+			return result
+		}
+	}
+
+	return cmp.Compare(len(aKeys), len(bKeys))
+}
+
+// firstError returns the first error of errors that were joined using joinErrors, or the error itself
+func firstError(err error) error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()[0]
+	}
+
+	return err
+}
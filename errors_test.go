@@ -0,0 +1,238 @@
+package deepgorm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ing-bank/gormtestutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddDeepFilters_ReturnsFilterErrors(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	tests := map[string]struct {
+		model       any
+		filterMap   map[string]any
+		expected    FilterError
+		expectedErr error
+	}{
+		"unknown field": {
+			model:       ComplexStruct1{},
+			filterMap:   map[string]any{"unknown": 5},
+			expected:    FilterError{Kind: KindUnknownField, Path: "unknown", Model: "ComplexStruct1", Value: 5},
+			expectedErr: ErrFieldDoesNotExist,
+		},
+		"unknown field in relation": {
+			model: ComplexStruct3{},
+			filterMap: map[string]any{
+				"tags": map[string]any{"tag_value": map[string]any{"unknown": "abc"}},
+			},
+			expected:    FilterError{Kind: KindUnknownField, Path: "tags.tag_value.unknown", Model: "TagValue", Value: "abc"},
+			expectedErr: ErrFieldDoesNotExist,
+		},
+		"unsupported operator": {
+			model:       ComplexStruct1{},
+			filterMap:   map[string]any{"value": map[string]any{"$near": 5}},
+			expected:    FilterError{Kind: KindUnsupportedOperator, Path: "value", Model: "ComplexStruct1", Value: map[string]any{"$near": 5}},
+			expectedErr: ErrUnsupportedOperator,
+		},
+		"invalid value in logical combinator": {
+			model: ComplexStruct1{},
+			filterMap: map[string]any{
+				"$or": []map[string]any{
+					{"value": 1},
+					{"nested": map[string]any{"name": map[string]any{"$like": 5}}},
+				},
+			},
+			expected:    FilterError{Kind: KindInvalidValue, Path: "$or.1.nested.name", Model: "NestedStruct4", Value: map[string]any{"$like": 5}},
+			expectedErr: ErrInvalidOperatorValue,
+		},
+		"unknown field in quantifier": {
+			model: ComplexStruct2{},
+			filterMap: map[string]any{
+				"$not": map[string]any{"tags": map[string]any{"$all": map[string]any{"unknown": "abc"}}},
+			},
+			expected:    FilterError{Kind: KindUnknownField, Path: "$not.tags.$all.unknown", Model: "SimpleTag", Value: "abc"},
+			expectedErr: ErrFieldDoesNotExist,
+		},
+		"unknown field in aggregate": {
+			model: Invoice{},
+			filterMap: map[string]any{
+				"line_items": map[string]any{"$sum": map[string]any{"price": 5}},
+			},
+			expected:    FilterError{Kind: KindUnknownField, Path: "line_items.$sum.price", Model: "LineItem", Value: 5},
+			expectedErr: ErrFieldDoesNotExist,
+		},
+		"unknown field in embedded struct": {
+			model: EmbeddedPerson{},
+			filterMap: map[string]any{
+				"address": map[string]any{"geo": map[string]any{"altitude": 5}},
+			},
+			expected:    FilterError{Kind: KindUnknownField, Path: "address.geo.altitude", Model: "EmbeddedPerson", Value: 5},
+			expectedErr: ErrFieldDoesNotExist,
+		},
+		"operator in embedded struct": {
+			model: EmbeddedPerson{},
+			filterMap: map[string]any{
+				"address": map[string]any{"geo": map[string]any{"lat": map[string]any{"$like": "5%"}}},
+			},
+			expected:    FilterError{Kind: KindUnsupportedOperator, Path: "address.geo.lat", Model: "EmbeddedPerson", Value: map[string]any{"$like": "5%"}},
+			expectedErr: ErrUnsupportedOperator,
+		},
+		"invalid value in json column": {
+			model: JSONService{},
+			filterMap: map[string]any{
+				"metadata": map[string]any{"labels": map[string]any{}},
+			},
+			expected:    FilterError{Kind: KindInvalidValue, Path: "metadata.labels", Model: "JSONService", Value: map[string]any{}},
+			expectedErr: ErrInvalidOperatorValue,
+		},
+		"invalid relation": {
+			model: RegisterItem{},
+			filterMap: map[string]any{
+				"tags": map[string]any{"name": "abc"},
+			},
+			expected:    FilterError{Kind: KindInvalidRelation, Path: "tags", Model: "RegisterItem", Value: map[string]any{"name": "abc"}},
+			expectedErr: ErrInvalidRelation,
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+
+			// Act
+			query, err := AddDeepFilters(database, testData.model, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, query)
+			assert.ErrorIs(t, err, testData.expectedErr)
+
+			var filterError *FilterError
+			require.True(t, errors.As(err, &filterError))
+			assert.Equal(t, testData.expected.Kind, filterError.Kind)
+			assert.Equal(t, testData.expected.Path, filterError.Path)
+			assert.Equal(t, testData.expected.Model, filterError.Model)
+			assert.Equal(t, testData.expected.Value, filterError.Value)
+		})
+	}
+}
+
+func TestDeepGorm_Initialize_ReturnsFilterErrors(t *testing.T) {
+	t.Parallel()
+	// Arrange
+	database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+	_ = database.Use(New())
+
+	// Act
+	err := database.Where(map[string]any{"object_bs": map[string]any{"unknown": "abc"}}).Find(&[]ObjectA{}).Error
+
+	// Assert
+	var filterError *FilterError
+	if assert.True(t, errors.As(err, &filterError)) {
+		assert.Equal(t, KindUnknownField, filterError.Kind)
+		assert.Equal(t, "object_bs.unknown", filterError.Path)
+	}
+}
+
+func TestFilterError_Error_ReturnsExpectedMessage(t *testing.T) {
+	t.Parallel()
+	// Arrange
+	database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+
+	// Act
+	_, err := AddDeepFilters(database, RegisterItem{}, map[string]any{"tags": map[string]any{"name": "abc"}})
+
+	// Assert
+	assert.EqualError(t, err, "failed to add filters for 'register_items.tags': no primary key found for the type of field Tags: invalid relation")
+}
+
+func TestDeepGorm_AddDeepFilters_ReturnsAllErrors(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	tests := map[string]struct {
+		options       []Option
+		expectedPaths []string
+	}{
+		"first error": {
+			expectedPaths: []string{"$or.0.unknown"},
+		},
+		"all errors": {
+			options: []Option{WithAllErrors()},
+			expectedPaths: []string{
+				"$or.0.unknown",
+				"$or.1.tags.$all.tag_value.nope",
+				"$or.1.tags.$count",
+				"name",
+				"tags.id",
+				"tags.key",
+				"zzz",
+			},
+		},
+	}
+
+	filterMap := map[string]any{
+		"zzz":  1,
+		"name": map[string]any{"$regex": "abc"},
+		"tags": map[string]any{
+			"key": map[string]any{"$like": 5},
+			"id":  map[string]any{"$between": []int{1}},
+		},
+		"$or": []map[string]any{
+			{"unknown": 1, "name": "abc"},
+			{"tags": map[string]any{
+				"$count": []int{1},
+				"$all":   map[string]any{"tag_value": map[string]any{"nope": 1}},
+			}},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			plugin := New(testData.options...)
+
+			// Act
+			query, err := plugin.AddDeepFilters(database, ComplexStruct3{}, filterMap)
+
+			// Assert
+			assert.Nil(t, query)
+
+			errs := []error{err}
+			if joined, ok := err.(interface{ Unwrap() []error }); ok {
+				errs = joined.Unwrap()
+			}
+
+			paths := make([]string, len(errs))
+			for index, err := range errs {
+				var filterError *FilterError
+				require.True(t, errors.As(err, &filterError))
+				paths[index] = filterError.Path
+			}
+
+			assert.Equal(t, testData.expectedPaths, paths)
+		})
+	}
+}
+
+func TestDeepGorm_Initialize_ReturnsAllErrors(t *testing.T) {
+	t.Parallel()
+	// Arrange
+	database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+	_ = database.Use(New(WithAllErrors()))
+
+	// Act
+	err := database.Where(map[string]any{"object_bs": map[string]any{"a": 1, "b": 2}}).Find(&[]ObjectA{}).Error
+
+	// Assert
+	assert.ErrorIs(t, err, ErrFieldDoesNotExist)
+	assert.EqualError(t, err, "failed to add filters for 'object_bs.a': field does not exist\nfailed to add filters for 'object_bs.b': field does not exist")
+}
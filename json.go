@@ -0,0 +1,193 @@
+package deepgorm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// isJSONColumn returns whether the column contains JSON, because of a serializer or a data type like datatypes.JSON
+func isJSONColumn(field *schema.Field) bool {
+	if serializer, ok := field.TagSettings["SERIALIZER"]; ok {
+		return strings.EqualFold(serializer, "json")
+	}
+
+	switch strings.ToLower(string(field.DataType)) {
+	case "json", "jsonb":
+		return true
+	}
+
+	return false
+}
+
+// jsonDataType returns the type that a value in a JSON document is compared as, lists use the type of their first item
+// and operator maps the type of their first operator's value.
+func jsonDataType(value any) schema.DataType {
+	if filter, ok := value.(map[string]any); ok && len(filter) > 0 {
+		return jsonDataType(filter[sortedKeys(filter)[0]])
+	}
+
+	reflectValue := reflect.ValueOf(value)
+
+	switch reflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		if reflectValue.Len() > 0 {
+			return jsonDataType(reflectValue.Index(0).Interface())
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return schema.Float
+	case reflect.Bool:
+		return schema.Bool
+	}
+
+	return schema.String
+}
+
+// jsonPath godoc
+// Extracts the value at the path from the JSON column, depending on the database:
+//
+//	SQLite:     json_extract(users.metadata, '$."labels"."env"')
+//	MySQL:      JSON_UNQUOTE(JSON_EXTRACT(users.metadata, '$."labels"."env"'))
+//	PostgreSQL: users.metadata -> 'labels' ->> 'env'
+//
+// MySQL returns strings with their JSON quotes, so they're unquoted to be able to compare them with strings and $like.
+// PostgreSQL returns text, so numbers and booleans are cast to be able to compare them.
+//
+// Keys are quoted, so they may contain dots. SQLite can't escape quotes in keys, so keys with a '"' are rejected there.
+func jsonPath(db *gorm.DB, column clause.Column, path []string, dataType schema.DataType) (clause.Expression, error) {
+	switch db.Dialector.Name() {
+	case "sqlite", "mysql":
+		function := "json_extract"
+		if db.Dialector.Name() == "mysql" {
+			function = "JSON_EXTRACT"
+		}
+
+		quoted := make([]string, len(path))
+		for index, key := range path {
+			switch {
+			case db.Dialector.Name() == "mysql":
+				// MySQL follows the rules of JSON strings in quoted keys
+				key = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(key)
+			case strings.Contains(key, `"`):
+				return nil, fmt.Errorf("JSON keys containing '\"' are not supported on sqlite: %w", ErrInvalidOperatorValue)
+			}
+
+			quoted[index] = `"` + key + `"`
+		}
+
+		sql := function + "(?, ?)"
+		if db.Dialector.Name() == "mysql" && dataType == schema.String {
+			sql = "JSON_UNQUOTE(" + sql + ")"
+		}
+
+		return clause.Expr{SQL: sql, Vars: []any{column, "$." + strings.Join(quoted, ".")}}, nil
+
+	case "postgres":
+		sql := "?" + strings.Repeat(" -> ?", len(path)-1) + " ->> ?"
+		vars := []any{column}
+		for _, key := range path {
+			vars = append(vars, key)
+		}
+
+		switch dataType {
+		case schema.Float:
+			sql = "(" + sql + ")::numeric"
+		case schema.Bool:
+			sql = "(" + sql + ")::boolean"
+		}
+
+		return clause.Expr{SQL: sql, Vars: vars}, nil
+	}
+
+	return nil, fmt.Errorf("JSON paths can't be used on '%s': %w", db.Dialector.Name(), ErrUnsupportedOperator)
+}
+
+// buildJSONExpression godoc
+// Turns a filter on a JSON column into conditions on the values in the document, nested maps are paths and the
+// values at the end of a path are compared with a value or a map of operators:
+//
+//	map[string]any{
+//		"metadata": map[string]any{
+//			"labels": map[string]any{"env": "prod"},
+//			"replicas": map[string]any{"$gte": 3},
+//		},
+//	}
+//
+// Refer to jsonPath for the resulting SQL.
+func buildJSONExpression(db *gorm.DB, schemaInfo *schema.Schema, field *schema.Field, path []string, filter map[string]any) (clause.Expression, error) {
+	if len(filter) == 0 {
+		name := strings.Join(append([]string{field.DBName}, path...), ".")
+		return nil, newFilterError(schemaInfo, name, filter, fmt.Errorf("expected a non-empty filter map: %w", ErrInvalidOperatorValue))
+	}
+
+	keys := sortedKeys(filter)
+
+	expressions := make([]clause.Expression, 0, len(keys))
+
+	var errs []error
+
+	for _, key := range keys {
+		expression, err := buildJSONKeyExpression(db, schemaInfo, field, append(append([]string{}, path...), key), filter[key])
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		expressions = append(expressions, expression)
+	}
+
+	if len(errs) > 0 {
+		return nil, joinErrors(errs)
+	}
+
+	return clause.And(expressions...), nil
+}
+
+// buildJSONKeyExpression turns the value at the path in the document into an expression, refer to buildJSONExpression
+func buildJSONKeyExpression(db *gorm.DB, schemaInfo *schema.Schema, field *schema.Field, keyPath []string, value any) (clause.Expression, error) {
+	// Nested objects in the document
+	if nestedFilter, ok := value.(map[string]any); ok && !isOperatorMap(nestedFilter) {
+		return buildJSONExpression(db, schemaInfo, field, keyPath, nestedFilter)
+	}
+
+	name := strings.Join(append([]string{field.DBName}, keyPath...), ".")
+
+	// NULL has no type, the path is used as it is
+	dataType := jsonDataType(value)
+	if value == nil {
+		dataType = ""
+	}
+
+	column, err := jsonPath(db, clause.Column{Table: schemaInfo.Table, Name: field.DBName}, keyPath, dataType)
+	if err != nil {
+		return nil, newFilterError(schemaInfo, name, value, err)
+	}
+
+	// Missing keys and null values, like {"metadata": {"owner": nil}}
+	if value == nil {
+		return jsonNull(db, column), nil
+	}
+
+	expression, err := compareValue(column, dataType, nil, keyPath[len(keyPath)-1], value)
+	if err != nil {
+		return nil, newFilterError(schemaInfo, name, value, err)
+	}
+
+	return expression, nil
+}
+
+// jsonNull checks whether the value at the path is missing or null. MySQL returns null values in documents as JSON
+// instead of NULL, so their type is checked as well.
+func jsonNull(db *gorm.DB, column clause.Expression) clause.Expression {
+	if db.Dialector.Name() == "mysql" {
+		return clause.Expr{SQL: "(? IS NULL OR JSON_TYPE(?) = 'NULL')", Vars: []any{column, column}}
+	}
+
+	return clause.Eq{Column: column, Value: nil}
+}
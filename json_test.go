@@ -0,0 +1,501 @@
+package deepgorm
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ing-bank/gormtestutil"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// JSONDocument behaves like datatypes.JSON
+type JSONDocument map[string]any
+
+func (j JSONDocument) Value() (driver.Value, error) {
+	return json.Marshal(j)
+}
+
+func (j *JSONDocument) Scan(value any) error {
+	data, ok := value.([]byte)
+	if !ok {
+		text, isString := value.(string)
+		if !isString {
+			return errors.New("invalid JSON")
+		}
+
+		data = []byte(text)
+	}
+
+	return json.Unmarshal(data, j)
+}
+
+func (JSONDocument) GormDataType() string {
+	return "json"
+}
+
+type JSONService struct {
+	ID        uuid.UUID
+	Name      string
+	Metadata  map[string]any `gorm:"serializer:json"`
+	Settings  JSONDocument
+	Tags      []string `gorm:"serializer:gob"`
+	ClusterID uuid.UUID
+}
+
+type JSONCluster struct {
+	ID       uuid.UUID
+	Name     string
+	Services []*JSONService `gorm:"foreignKey:ClusterID"`
+}
+
+func TestIsJSONColumn_ReturnsExpectedResult(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	tests := map[string]struct {
+		field    string
+		expected bool
+	}{
+		"serializer": {
+			field:    "Metadata",
+			expected: true,
+		},
+		"data type": {
+			field:    "Settings",
+			expected: true,
+		},
+		"other serializer": {
+			field:    "Tags",
+			expected: false,
+		},
+		"simple column": {
+			field:    "Name",
+			expected: false,
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			naming := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name())).NamingStrategy
+			schemaInfo, _ := schema.Parse(JSONService{}, &sync.Map{}, naming)
+
+			// Act
+			result := isJSONColumn(schemaInfo.FieldsByName[testData.field])
+
+			// Assert
+			assert.Equal(t, testData.expected, result)
+		})
+	}
+}
+
+func TestJSONPath_ReturnsExpectedSQL(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	tests := map[string]struct {
+		dialect      string
+		path         []string
+		dataType     schema.DataType
+		expectedSQL  string
+		expectedVars []any
+	}{
+		"sqlite": {
+			dialect:      "sqlite",
+			dataType:     schema.String,
+			expectedSQL:  "json_extract(`services`.`metadata`, ?)",
+			expectedVars: []any{`$."labels"."env"`},
+		},
+		"mysql": {
+			dialect:      "mysql",
+			dataType:     schema.String,
+			expectedSQL:  "JSON_UNQUOTE(JSON_EXTRACT(`services`.`metadata`, ?))",
+			expectedVars: []any{`$."labels"."env"`},
+		},
+		"mysql number": {
+			dialect:      "mysql",
+			dataType:     schema.Float,
+			expectedSQL:  "JSON_EXTRACT(`services`.`metadata`, ?)",
+			expectedVars: []any{`$."labels"."env"`},
+		},
+		"postgres": {
+			dialect:      "postgres",
+			dataType:     schema.String,
+			expectedSQL:  "`services`.`metadata` -> ? ->> ?",
+			expectedVars: []any{"labels", "env"},
+		},
+		"postgres number": {
+			dialect:      "postgres",
+			dataType:     schema.Float,
+			expectedSQL:  "(`services`.`metadata` -> ? ->> ?)::numeric",
+			expectedVars: []any{"labels", "env"},
+		},
+		"postgres boolean": {
+			dialect:      "postgres",
+			dataType:     schema.Bool,
+			expectedSQL:  "(`services`.`metadata` -> ? ->> ?)::boolean",
+			expectedVars: []any{"labels", "env"},
+		},
+		"sqlite dot in key": {
+			dialect:      "sqlite",
+			path:         []string{"labels", "app.kubernetes.io/name"},
+			dataType:     schema.String,
+			expectedSQL:  "json_extract(`services`.`metadata`, ?)",
+			expectedVars: []any{`$."labels"."app.kubernetes.io/name"`},
+		},
+		"mysql quote in key": {
+			dialect:      "mysql",
+			path:         []string{"labels", `say "hi" \o/`},
+			dataType:     schema.Float,
+			expectedSQL:  "JSON_EXTRACT(`services`.`metadata`, ?)",
+			expectedVars: []any{`$."labels"."say \"hi\" \\o/"`},
+		},
+		"postgres quote in key": {
+			dialect:      "postgres",
+			path:         []string{"labels", `say "hi"`},
+			dataType:     schema.String,
+			expectedSQL:  "`services`.`metadata` -> ? ->> ?",
+			expectedVars: []any{"labels", `say "hi"`},
+		},
+		"mysql without type": {
+			dialect:      "mysql",
+			expectedSQL:  "JSON_EXTRACT(`services`.`metadata`, ?)",
+			expectedVars: []any{`$."labels"."env"`},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			database.Dialector = namedDialector{Dialector: database.Dialector, name: testData.dialect}
+
+			statement := &gorm.Statement{DB: database, Clauses: map[string]clause.Clause{}}
+
+			path := testData.path
+			if path == nil {
+				path = []string{"labels", "env"}
+			}
+
+			// Act
+			result, err := jsonPath(database, clause.Column{Table: "services", Name: "metadata"}, path, testData.dataType)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, result) {
+				result.Build(statement)
+
+				assert.Equal(t, testData.expectedSQL, statement.SQL.String())
+				assert.Equal(t, testData.expectedVars, statement.Vars)
+			}
+		})
+	}
+}
+
+func TestJSONNull_ReturnsExpectedSQL(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	tests := map[string]struct {
+		dialect      string
+		expectedSQL  string
+		expectedVars []any
+	}{
+		"sqlite": {
+			dialect:      "sqlite",
+			expectedSQL:  "json_extract(`services`.`metadata`, ?) IS NULL",
+			expectedVars: []any{`$."labels"."env"`},
+		},
+		"mysql": {
+			dialect:      "mysql",
+			expectedSQL:  "(JSON_EXTRACT(`services`.`metadata`, ?) IS NULL OR JSON_TYPE(JSON_EXTRACT(`services`.`metadata`, ?)) = 'NULL')",
+			expectedVars: []any{`$."labels"."env"`, `$."labels"."env"`},
+		},
+		"postgres": {
+			dialect:      "postgres",
+			expectedSQL:  "`services`.`metadata` -> ? ->> ? IS NULL",
+			expectedVars: []any{"labels", "env"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			database.Dialector = namedDialector{Dialector: database.Dialector, name: testData.dialect}
+
+			statement := &gorm.Statement{DB: database, Clauses: map[string]clause.Clause{}}
+
+			column, _ := jsonPath(database, clause.Column{Table: "services", Name: "metadata"}, []string{"labels", "env"}, "")
+
+			// Act
+			result := jsonNull(database, column)
+
+			// Assert
+			result.Build(statement)
+
+			assert.Equal(t, testData.expectedSQL, statement.SQL.String())
+			assert.Equal(t, testData.expectedVars, statement.Vars)
+		})
+	}
+}
+
+func TestAddDeepFilters_AddsJSONFilters(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	records := []*JSONCluster{
+		{
+			ID:   uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+			Name: "production",
+			Services: []*JSONService{
+				{
+					ID:       uuid.MustParse("1c83a7c9-e95d-4dba-b858-5eb4e34ebcf2"),
+					Name:     "api",
+					Metadata: map[string]any{"labels": map[string]any{"env": "prod", "team": "core", "owner": "ops", "app.kubernetes.io/name": "api"}, "replicas": 5, "public": true},
+					Settings: JSONDocument{"log": map[string]any{"level": "warn"}},
+				},
+			},
+		},
+		{
+			ID:   uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+			Name: "staging",
+			Services: []*JSONService{
+				{
+					ID:       uuid.MustParse("17983ba8-2d26-4e36-bb6b-6c5a04b6606e"),
+					Name:     "worker",
+					Metadata: map[string]any{"labels": map[string]any{"env": "test", "team": "core"}, "replicas": 1, "public": false},
+					Settings: JSONDocument{"log": map[string]any{"level": "debug"}},
+				},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		objectType    any
+		filterMap     map[string]any
+		expectedNames []string
+	}{
+		"nested path": {
+			objectType: JSONService{},
+			filterMap: map[string]any{
+				"metadata": map[string]any{
+					"labels": map[string]any{"env": "prod"},
+				},
+			},
+			expectedNames: []string{"api"},
+		},
+		"multiple paths": {
+			objectType: JSONService{},
+			filterMap: map[string]any{
+				"metadata": map[string]any{
+					"labels": map[string]any{"team": "core"},
+					"public": false,
+				},
+			},
+			expectedNames: []string{"worker"},
+		},
+		"operators": {
+			objectType: JSONService{},
+			filterMap: map[string]any{
+				"metadata": map[string]any{
+					"replicas": map[string]any{"$gte": 3},
+				},
+			},
+			expectedNames: []string{"api"},
+		},
+		"like": {
+			objectType: JSONService{},
+			filterMap: map[string]any{
+				"metadata": map[string]any{
+					"labels": map[string]any{
+						"env": map[string]any{"$like": "te%"},
+					},
+				},
+			},
+			expectedNames: []string{"worker"},
+		},
+		"data type": {
+			objectType: JSONService{},
+			filterMap: map[string]any{
+				"settings": map[string]any{
+					"log": map[string]any{"level": "debug"},
+				},
+			},
+			expectedNames: []string{"worker"},
+		},
+		"null": {
+			objectType: JSONService{},
+			filterMap: map[string]any{
+				"metadata": map[string]any{
+					"labels": map[string]any{"owner": nil},
+				},
+			},
+			expectedNames: []string{"worker"},
+		},
+		"not null": {
+			objectType: JSONService{},
+			filterMap: map[string]any{
+				"$not": map[string]any{
+					"metadata": map[string]any{
+						"labels": map[string]any{"owner": nil},
+					},
+				},
+			},
+			expectedNames: []string{"api"},
+		},
+		"dot in key": {
+			objectType: JSONService{},
+			filterMap: map[string]any{
+				"metadata": map[string]any{
+					"labels": map[string]any{"app.kubernetes.io/name": "api"},
+				},
+			},
+			expectedNames: []string{"api"},
+		},
+		"not": {
+			objectType: JSONService{},
+			filterMap: map[string]any{
+				"$not": map[string]any{
+					"metadata": map[string]any{
+						"labels": map[string]any{"env": "prod"},
+					},
+				},
+			},
+			expectedNames: []string{"worker"},
+		},
+		"in relation": {
+			objectType: JSONCluster{},
+			filterMap: map[string]any{
+				"services": map[string]any{
+					"metadata": map[string]any{
+						"replicas": map[string]any{"$lt": 3},
+					},
+				},
+			},
+			expectedNames: []string{"staging"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&JSONCluster{}, &JSONService{})
+
+			database.CreateInBatches(records, len(records))
+
+			// Act
+			query, err := AddDeepFilters(database, testData.objectType, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []string
+				res := query.Model(testData.objectType).Order("name").Pluck("name", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, testData.expectedNames, result)
+			}
+		})
+	}
+}
+
+func TestAddDeepFilters_ReturnsErrorOnInvalidJSONFilters(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	tests := map[string]struct {
+		dialect       string
+		filterMap     map[string]any
+		expectedError error
+		expectedMsg   string
+	}{
+		"empty map": {
+			filterMap: map[string]any{
+				"metadata": map[string]any{
+					"labels": map[string]any{},
+				},
+			},
+			expectedError: ErrInvalidOperatorValue,
+			expectedMsg:   "failed to add filters for 'json_services.metadata.labels': expected a non-empty filter map: invalid operator value",
+		},
+		"operator on wrong type": {
+			filterMap: map[string]any{
+				"metadata": map[string]any{
+					"replicas": map[string]any{"$like": 5},
+				},
+			},
+			expectedError: ErrUnsupportedOperator,
+			expectedMsg:   "failed to add filters for 'json_services.metadata.replicas': '$like' can't be used on type 'float': unsupported operator",
+		},
+		"list": {
+			filterMap: map[string]any{
+				"metadata": map[string]any{
+					"replicas": []int{1, 2},
+				},
+			},
+			expectedError: ErrInvalidOperatorValue,
+			expectedMsg:   "failed to add filters for 'json_services.metadata.replicas': 'replicas' expects a single value: invalid operator value",
+		},
+		"not a JSON column": {
+			filterMap: map[string]any{
+				"name": map[string]any{"first": "api"},
+			},
+			expectedError: ErrFieldDoesNotExist,
+			expectedMsg:   "failed to add filters for 'json_services.name': field does not exist",
+		},
+		"quote in key on sqlite": {
+			filterMap: map[string]any{
+				"metadata": map[string]any{
+					"labels": map[string]any{`say "hi"`: "api"},
+				},
+			},
+			expectedError: ErrInvalidOperatorValue,
+			expectedMsg:   "failed to add filters for 'json_services.metadata.labels.say \"hi\"': JSON keys containing '\"' are not supported on sqlite: invalid operator value",
+		},
+		"unsupported database": {
+			dialect: "sqlserver",
+			filterMap: map[string]any{
+				"metadata": map[string]any{"replicas": 1},
+			},
+			expectedError: ErrUnsupportedOperator,
+			expectedMsg:   "failed to add filters for 'json_services.metadata.replicas': JSON paths can't be used on 'sqlserver': unsupported operator",
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+
+			if testData.dialect != "" {
+				database.Dialector = namedDialector{Dialector: database.Dialector, name: testData.dialect}
+			}
+
+			// Act
+			query, err := AddDeepFilters(database, JSONService{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, query)
+			assert.ErrorIs(t, err, testData.expectedError)
+			assert.EqualError(t, err, testData.expectedMsg)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package deepgorm
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// complementAlias is the alias of the related table in EXISTS-subqueries that select the records that don't match
+const complementAlias = "deepgorm_complement"
+
+// defaultRowValueDialects are the databases that support row values like (a, b) IN (SELECT x, y ...), other
+// databases get EXISTS-based subqueries for relations with composite keys.
+var defaultRowValueDialects = map[string]bool{
+	"sqlite":   true,
+	"postgres": true,
+	"mysql":    true,
+}
+
+// useRowValues returns whether the keys can be compared using IN, single keys always can
+func (d *DeepGorm) useRowValues(db *gorm.DB, keys []string) bool {
+	return len(keys) == 1 || d.rowValueDialects[db.Dialector.Name()]
+}
+
+// columnsOf qualifies the column names with the given table
+func columnsOf(table string, names []string) []clause.Column {
+	result := make([]clause.Column, len(names))
+	for index, name := range names {
+		result[index] = clause.Column{Table: table, Name: name}
+	}
+
+	return result
+}
+
+// rowValue turns the columns into a single column, or a row value like (a, b) if there are multiple
+func rowValue(columns []clause.Column) clause.Expression {
+	vars := make([]any, len(columns))
+	for index, column := range columns {
+		vars[index] = column
+	}
+
+	if len(columns) == 1 {
+		return clause.Expr{SQL: "?", Vars: vars}
+	}
+
+	return clause.Expr{SQL: "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")", Vars: vars}
+}
+
+// inSubquery creates '<columns> IN (<subquery>)' or '<columns> NOT IN (<subquery>)'
+func inSubquery(columns []clause.Column, subQuery *gorm.DB, not bool) clause.Expression {
+	sql := "? IN (?)"
+	if not {
+		sql = "? NOT IN (?)"
+	}
+
+	return clause.Expr{SQL: sql, Vars: []any{rowValue(columns), subQuery}}
+}
+
+// existsSubquery creates 'EXISTS (<subquery>)' or 'NOT EXISTS (<subquery>)'
+func existsSubquery(subQuery *gorm.DB, not bool) clause.Expression {
+	sql := "EXISTS (?)"
+	if not {
+		sql = "NOT EXISTS (?)"
+	}
+
+	return clause.Expr{SQL: sql, Vars: []any{subQuery}}
+}
+
+// columnsEqual pairs up the columns on both sides using AND, used to correlate subqueries
+func columnsEqual(left []clause.Column, right []clause.Column) clause.Expression {
+	expressions := make([]clause.Expression, len(left))
+	for index := range left {
+		expressions[index] = clause.Eq{Column: left[index], Value: right[index]}
+	}
+
+	return clause.And(expressions...)
+}
+
+// anyNull is true if any of the columns is NULL
+func anyNull(columns []clause.Column) clause.Expression {
+	expressions := make([]clause.Expression, len(columns))
+	for index, column := range columns {
+		expressions[index] = clause.Expr{SQL: "? IS NULL", Vars: []any{column}}
+	}
+
+	return or(expressions...)
+}
+
+// noneNull is true if none of the columns are NULL
+func noneNull(columns []clause.Column) clause.Expression {
+	expressions := make([]clause.Expression, len(columns))
+	for index, column := range columns {
+		expressions[index] = clause.Expr{SQL: "? IS NOT NULL", Vars: []any{column}}
+	}
+
+	return clause.And(expressions...)
+}
+
+// correlatedRecords selects the related records of which the columns match the given columns of the outer query,
+// to be used in EXISTS-subqueries. The filter in subQuery refers to the related table by its name, so in
+// self-referential relations the related table is aliased, otherwise the outer table can't be referred to anymore:
+//
+//	SELECT 1 FROM users deepgorm_related WHERE deepgorm_related.manager_id = users.id
+//	  AND EXISTS (SELECT 1 FROM users WHERE users.id = deepgorm_related.id AND <filter>)
+func correlatedRecords(cleanDB *gorm.DB, schemaInfo *schema.Schema, fieldInfo *nestedType, columns []string, outer []clause.Column, subQuery *gorm.DB) *gorm.DB {
+	if fieldInfo.fieldTable != schemaInfo.Table {
+		return cleanDB.Model(fieldInfo.fieldStructInstance).Select("1").Where(columnsEqual(columnsOf(fieldInfo.fieldTable, columns), outer)).Where(subQuery)
+	}
+
+	aliasedKeys := columnsOf(correlatedAlias, fieldInfo.fieldPrimaryKeys)
+	matching := cleanDB.Model(fieldInfo.fieldStructInstance).Select("1").Where(columnsEqual(columnsOf(fieldInfo.fieldTable, fieldInfo.fieldPrimaryKeys), aliasedKeys)).Where(subQuery)
+
+	return cleanDB.Table("?", clause.Table{Name: fieldInfo.fieldTable, Alias: correlatedAlias}).Select("1").Where(columnsEqual(columnsOf(correlatedAlias, columns), outer)).Where(existsSubquery(matching, false))
+}
@@ -0,0 +1,407 @@
+package deepgorm
+
+import (
+	"testing"
+
+	"github.com/ing-bank/gormtestutil"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type CompositeTag struct {
+	TenantID string `gorm:"primaryKey"`
+	ID       string `gorm:"primaryKey"`
+	Name     string
+}
+
+type CompositeLine struct {
+	TenantID string `gorm:"primaryKey"`
+	ID       string `gorm:"primaryKey"`
+	OrderID  string
+	Product  string
+	Amount   int
+	Order    *CompositeOrder `gorm:"foreignKey:TenantID,OrderID;references:TenantID,ID"`
+}
+
+type CompositeOrder struct {
+	TenantID string `gorm:"primaryKey"`
+	ID       string `gorm:"primaryKey"`
+	Name     string
+	Lines    []*CompositeLine `gorm:"foreignKey:TenantID,OrderID;references:TenantID,ID"`
+	Tags     []*CompositeTag  `gorm:"many2many:composite_order_tags"`
+}
+
+type CompositeEmployee struct {
+	TenantID  string `gorm:"primaryKey"`
+	ID        string `gorm:"primaryKey"`
+	Name      string
+	ManagerID *string
+	Manager   *CompositeEmployee   `gorm:"foreignKey:TenantID,ManagerID;references:TenantID,ID"`
+	Reports   []*CompositeEmployee `gorm:"foreignKey:TenantID,ManagerID;references:TenantID,ID"`
+}
+
+// namedDialector pretends to be another database, for example one that doesn't support row values
+type namedDialector struct {
+	gorm.Dialector
+	name string
+}
+
+func (n namedDialector) Name() string {
+	return n.name
+}
+
+func TestUseRowValues_ReturnsExpectedResult(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	tests := map[string]struct {
+		exists   bool
+		keys     []string
+		expected bool
+	}{
+		"single key": {
+			keys:     []string{"id"},
+			expected: true,
+		},
+		"single key without row values": {
+			exists:   true,
+			keys:     []string{"id"},
+			expected: true,
+		},
+		"composite key": {
+			keys:     []string{"tenant_id", "id"},
+			expected: true,
+		},
+		"composite key without row values": {
+			exists:   true,
+			keys:     []string{"tenant_id", "id"},
+			expected: false,
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+
+			if testData.exists {
+				database.Dialector = namedDialector{Dialector: database.Dialector, name: "exists"}
+			}
+
+			// Act
+			result := New().useRowValues(database, testData.keys)
+
+			// Assert
+			assert.Equal(t, testData.expected, result)
+		})
+	}
+}
+
+func TestAddDeepFilters_AddsDeepFiltersWithCompositeKeys(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	// Both tenants use the same ids, so only matching both columns gives the right result
+	records := []*CompositeOrder{
+		{
+			TenantID: "a",
+			ID:       "1",
+			Name:     "first",
+			Lines: []*CompositeLine{
+				{ID: "1", Product: "apple", Amount: 5},
+				{ID: "2", Product: "pear", Amount: 10},
+			},
+			Tags: []*CompositeTag{{TenantID: "a", ID: "1", Name: "red"}},
+		},
+		{
+			TenantID: "b",
+			ID:       "1",
+			Name:     "second",
+			Lines: []*CompositeLine{
+				{ID: "1", Product: "pear", Amount: 20},
+			},
+			Tags: []*CompositeTag{{TenantID: "b", ID: "1", Name: "blue"}},
+		},
+		{
+			TenantID: "b",
+			ID:       "2",
+			Name:     "third",
+		},
+	}
+
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedNames []string
+	}{
+		"has many": {
+			filterMap: map[string]any{
+				"lines": map[string]any{"product": "apple"},
+			},
+			expectedNames: []string{"first"},
+		},
+		"not has many": {
+			filterMap: map[string]any{
+				"$not": map[string]any{
+					"lines": map[string]any{"product": "apple"},
+				},
+			},
+			expectedNames: []string{"second", "third"},
+		},
+		"has many all": {
+			filterMap: map[string]any{
+				"lines": map[string]any{
+					"$all": map[string]any{"product": "pear"},
+					"$any": map[string]any{},
+				},
+			},
+			expectedNames: []string{"second"},
+		},
+		"has many count": {
+			filterMap: map[string]any{
+				"lines": map[string]any{"$count": 1},
+			},
+			expectedNames: []string{"second"},
+		},
+		"has many sum": {
+			filterMap: map[string]any{
+				"lines": map[string]any{
+					"$sum": map[string]any{"amount": 15},
+				},
+			},
+			expectedNames: []string{"first"},
+		},
+		"many to many": {
+			filterMap: map[string]any{
+				"tags": map[string]any{"name": "blue"},
+			},
+			expectedNames: []string{"second"},
+		},
+		"not many to many": {
+			filterMap: map[string]any{
+				"$not": map[string]any{
+					"tags": map[string]any{"name": "blue"},
+				},
+			},
+			expectedNames: []string{"first", "third"},
+		},
+		"many to many count": {
+			filterMap: map[string]any{
+				"tags": map[string]any{"$count": 0},
+			},
+			expectedNames: []string{"third"},
+		},
+	}
+
+	for _, exists := range []bool{false, true} {
+		for name, testData := range tests {
+			exists, testData := exists, testData
+			if exists {
+				name += " using exists"
+			}
+
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				// Arrange
+				database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+				_ = database.AutoMigrate(&CompositeOrder{}, &CompositeLine{}, &CompositeTag{})
+
+				database.CreateInBatches(records, len(records))
+
+				if exists {
+					database.Dialector = namedDialector{Dialector: database.Dialector, name: "exists"}
+				}
+
+				// Act
+				query, err := AddDeepFilters(database, CompositeOrder{}, testData.filterMap)
+
+				// Assert
+				assert.Nil(t, err)
+
+				if assert.NotNil(t, query) {
+					var result []string
+					res := query.Model(&CompositeOrder{}).Order("name").Pluck("name", &result)
+
+					// Handle error
+					assert.Nil(t, res.Error)
+
+					assert.Equal(t, testData.expectedNames, result)
+				}
+			})
+		}
+	}
+}
+
+func TestAddDeepFilters_AddsDeepFiltersWithCompositeBelongsTo(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	records := []*CompositeOrder{
+		{
+			TenantID: "a",
+			ID:       "1",
+			Name:     "first",
+			Lines: []*CompositeLine{
+				{ID: "1", Product: "apple"},
+			},
+		},
+		{
+			TenantID: "b",
+			ID:       "1",
+			Name:     "second",
+			Lines: []*CompositeLine{
+				{ID: "1", Product: "pear"},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		filterMap        map[string]any
+		expectedProducts []string
+	}{
+		"belongs to": {
+			filterMap: map[string]any{
+				"order": map[string]any{"name": "first"},
+			},
+			expectedProducts: []string{"apple"},
+		},
+		"not belongs to": {
+			filterMap: map[string]any{
+				"$not": map[string]any{
+					"order": map[string]any{"name": "first"},
+				},
+			},
+			expectedProducts: []string{"pear"},
+		},
+	}
+
+	for _, exists := range []bool{false, true} {
+		for name, testData := range tests {
+			exists, testData := exists, testData
+			if exists {
+				name += " using exists"
+			}
+
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				// Arrange
+				database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+				_ = database.AutoMigrate(&CompositeOrder{}, &CompositeLine{}, &CompositeTag{})
+
+				database.CreateInBatches(records, len(records))
+
+				if exists {
+					database.Dialector = namedDialector{Dialector: database.Dialector, name: "exists"}
+				}
+
+				// Act
+				query, err := AddDeepFilters(database, CompositeLine{}, testData.filterMap)
+
+				// Assert
+				assert.Nil(t, err)
+
+				if assert.NotNil(t, query) {
+					var result []string
+					res := query.Model(&CompositeLine{}).Order("product").Pluck("product", &result)
+
+					// Handle error
+					assert.Nil(t, res.Error)
+
+					assert.Equal(t, testData.expectedProducts, result)
+				}
+			})
+		}
+	}
+}
+
+func TestAddDeepFilters_AddsDeepFiltersWithCompositeSelfReferences(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	one, two := "1", "2"
+
+	// Both tenants use the same ids, so only matching both columns gives the right result
+	records := []*CompositeEmployee{
+		{TenantID: "a", ID: "1", Name: "Alice"},
+		{TenantID: "a", ID: "2", Name: "Bob", ManagerID: &one},
+		{TenantID: "a", ID: "3", Name: "Carol", ManagerID: &two},
+		{TenantID: "b", ID: "1", Name: "Dave"},
+		{TenantID: "b", ID: "2", Name: "Erin", ManagerID: &one},
+	}
+
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedNames []string
+	}{
+		"belongs to": {
+			filterMap: map[string]any{
+				"manager": map[string]any{"name": "Alice"},
+			},
+			expectedNames: []string{"Bob"},
+		},
+		"not belongs to": {
+			filterMap: map[string]any{
+				"$not": map[string]any{
+					"manager": map[string]any{"name": "Alice"},
+				},
+			},
+			expectedNames: []string{"Alice", "Carol", "Dave", "Erin"},
+		},
+		"has many": {
+			filterMap: map[string]any{
+				"reports": map[string]any{"name": "Erin"},
+			},
+			expectedNames: []string{"Dave"},
+		},
+		"has many all": {
+			filterMap: map[string]any{
+				"reports": map[string]any{
+					"$all": map[string]any{"name": "Bob"},
+					"$any": map[string]any{},
+				},
+			},
+			expectedNames: []string{"Alice"},
+		},
+		"has many count": {
+			filterMap: map[string]any{
+				"reports": map[string]any{"$count": 0},
+			},
+			expectedNames: []string{"Carol", "Erin"},
+		},
+	}
+
+	for _, exists := range []bool{false, true} {
+		for name, testData := range tests {
+			exists, testData := exists, testData
+			if exists {
+				name += " using exists"
+			}
+
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				// Arrange
+				database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+				_ = database.AutoMigrate(&CompositeEmployee{})
+
+				database.CreateInBatches(records, len(records))
+
+				if exists {
+					database.Dialector = namedDialector{Dialector: database.Dialector, name: "exists"}
+				}
+
+				// Act
+				query, err := AddDeepFilters(database, CompositeEmployee{}, testData.filterMap)
+
+				// Assert
+				assert.Nil(t, err)
+
+				if assert.NotNil(t, query) {
+					var result []string
+					res := query.Model(&CompositeEmployee{}).Order("name").Pluck("name", &result)
+
+					// Handle error
+					assert.Nil(t, res.Error)
+
+					assert.Equal(t, testData.expectedNames, result)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package deepgorm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrLimitExceeded is returned if a filter exceeds one of the limits, refer to Limits
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// LimitKind is the limit that a filter exceeds, refer to LimitError
+type LimitKind string
+
+const (
+	// LimitDepth is the number of nested filter maps, refer to Limits.MaxDepth
+	LimitDepth LimitKind = "depth"
+
+	// LimitPredicates is the number of conditions, refer to Limits.MaxPredicates
+	LimitPredicates LimitKind = "number of predicates"
+
+	// LimitSubqueries is the number of subqueries, refer to Limits.MaxSubqueries
+	LimitSubqueries LimitKind = "number of subqueries"
+
+	// LimitListSize is the number of values in a list, refer to Limits.MaxListSize
+	LimitListSize LimitKind = "list size"
+)
+
+// Limits godoc
+// Limits the size of the filters that may be used, refer to WithLimits. Filters are checked before any SQL is built,
+// so a client can't make the database do a lot of work by sending a huge filter. Limits that are 0 aren't checked.
+//
+//	deepgorm.Limits{
+//		// {"group": {"owner": {"name": "Jake"}}} is fine, {"group": {"owner": {"pets": {...}}}} isn't
+//		MaxDepth: 2,
+//		// {"name": "Jake", "age": {"$gt": 18, "$lt": 65}} has 3
+//		MaxPredicates: 20,
+//		// {"group": {...}, "pets": {"$count": 3}} has 2
+//		MaxSubqueries: 5,
+//		// {"id": [1, 2, 3]} has 3
+//		MaxListSize: 100,
+//	}
+type Limits struct {
+	// MaxDepth is the maximum number of nested filter maps, like the ones of relations, embedded structs, quantifiers,
+	// logical combinators and objects in JSON columns
+	MaxDepth int
+
+	// MaxPredicates is the maximum number of conditions, every value, operator and relation operator counts
+	MaxPredicates int
+
+	// MaxSubqueries is the maximum number of subqueries, every filter on a relation, quantifier and aggregate counts
+	MaxSubqueries int
+
+	// MaxListSize is the maximum number of values in a list, like {"id": [1, 2, 3]}
+	MaxListSize int
+}
+
+// LimitError godoc
+// Returned by AddDeepFilters if a filter exceeds one of the limits, it can be retrieved using errors.As:
+//
+//	var limitError *deepgorm.LimitError
+//	if errors.As(err, &limitError) {
+//		// limitError.Kind is deepgorm.LimitDepth, limitError.Path is 'group.owner.pets'
+//	}
+//
+// It matches ErrLimitExceeded using errors.Is.
+type LimitError struct {
+	// Kind is the limit that was exceeded
+	Kind LimitKind
+
+	// Max is the configured limit
+	Max int
+
+	// Path is the path of keys in the filter map where the limit was exceeded, like group.owner.pets
+	Path string
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("filter exceeds the maximum %s of %d at '%s': %v", e.Kind, e.Max, e.Path, ErrLimitExceeded)
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// listSize returns the number of values in the list, or -1 if the value isn't a list. Bytes like []byte("abc") are
+// a single value.
+func listSize(value any) int {
+	reflectValue := reflect.ValueOf(value)
+
+	switch reflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		if reflectValue.Type().Elem().Kind() == reflect.Uint8 {
+			return -1
+		}
+
+		return reflectValue.Len()
+	}
+
+	return -1
+}
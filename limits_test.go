@@ -0,0 +1,325 @@
+package deepgorm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ing-bank/gormtestutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type LimitOwner struct {
+	ID   uuid.UUID
+	Name string
+}
+
+type LimitGroup struct {
+	ID      uuid.UUID
+	Name    string
+	OwnerID *uuid.UUID
+	Owner   *LimitOwner
+}
+
+type LimitPet struct {
+	ID          uuid.UUID
+	LimitUserID uuid.UUID
+	Name        string
+	Age         int
+}
+
+type LimitUser struct {
+	ID      uuid.UUID
+	Name    string
+	Age     int
+	Avatar  []byte
+	GroupID *uuid.UUID
+	Group   *LimitGroup
+	Pets    []*LimitPet
+}
+
+func TestDeepGorm_AddDeepFilters_AllowsFiltersWithinLimits(t *testing.T) {
+	t.Parallel()
+	records := []*LimitUser{
+		{
+			ID:    uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+			Name:  "Jake",
+			Age:   30,
+			Group: &LimitGroup{ID: uuid.New(), Name: "admins", Owner: &LimitOwner{ID: uuid.New(), Name: "Anna"}},
+			Pets:  []*LimitPet{{ID: uuid.New(), Name: "Bello", Age: 3}},
+		},
+		{
+			ID:   uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+			Name: "Anna",
+			Age:  20,
+		},
+	}
+
+	tests := map[string]struct {
+		limits    Limits
+		filterMap map[string]any
+		expected  []string
+	}{
+		"depth": {
+			limits:    Limits{MaxDepth: 2},
+			filterMap: map[string]any{"group": map[string]any{"owner": map[string]any{"name": "Anna"}}},
+			expected:  []string{"Jake"},
+		},
+		"predicates": {
+			limits:    Limits{MaxPredicates: 3},
+			filterMap: map[string]any{"age": map[string]any{"$gt": 18, "$lt": 65}, "name": "Anna"},
+			expected:  []string{"Anna"},
+		},
+		"subqueries": {
+			limits:    Limits{MaxSubqueries: 2},
+			filterMap: map[string]any{"group": nil, "pets": map[string]any{"$count": 0, "$exists": false}},
+			expected:  []string{"Anna"},
+		},
+		"list size": {
+			limits:    Limits{MaxListSize: 2},
+			filterMap: map[string]any{"name": []string{"Anna", "Jake"}, "avatar": []byte("not a list"), "age": map[string]any{"$between": []int{10, 25}}},
+			expected:  []string{},
+		},
+		"all limits": {
+			limits: Limits{MaxDepth: 2, MaxPredicates: 4, MaxSubqueries: 3, MaxListSize: 2},
+			filterMap: map[string]any{"$or": []map[string]any{
+				{"name": []string{"Anna", "Jake"}},
+				{"pets": map[string]any{"name": "Bello"}, "group": map[string]any{"name": "admins"}},
+			}},
+			expected: []string{"Anna", "Jake"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&LimitUser{}, &LimitGroup{}, &LimitOwner{}, &LimitPet{})
+
+			database.CreateInBatches(records, len(records))
+
+			plugin := New(WithLimits(testData.limits))
+
+			// Act
+			query, err := plugin.AddDeepFilters(database, LimitUser{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []string
+				res := query.Model(&LimitUser{}).Order("name").Pluck("name", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, testData.expected, result)
+			}
+		})
+	}
+}
+
+func TestDeepGorm_AddDeepFilters_ReturnsErrorOnExceededLimits(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		limits    Limits
+		filterMap map[string]any
+		expected  LimitError
+	}{
+		"depth of relations": {
+			limits:    Limits{MaxDepth: 1},
+			filterMap: map[string]any{"group": map[string]any{"owner": map[string]any{"name": "Anna"}}},
+			expected:  LimitError{Kind: LimitDepth, Max: 1, Path: "group.owner"},
+		},
+		"depth of logical combinators": {
+			limits:    Limits{MaxDepth: 2},
+			filterMap: map[string]any{"$not": map[string]any{"$not": map[string]any{"$not": map[string]any{"name": "Anna"}}}},
+			expected:  LimitError{Kind: LimitDepth, Max: 2, Path: "$not.$not.$not"},
+		},
+		"depth of quantifiers": {
+			limits:    Limits{MaxDepth: 1},
+			filterMap: map[string]any{"pets": map[string]any{"$all": map[string]any{"name": "Bello"}}},
+			expected:  LimitError{Kind: LimitDepth, Max: 1, Path: "pets.$all"},
+		},
+		"predicates": {
+			limits:    Limits{MaxPredicates: 2},
+			filterMap: map[string]any{"age": map[string]any{"$gt": 18, "$lt": 65}, "name": "Anna"},
+			expected:  LimitError{Kind: LimitPredicates, Max: 2, Path: "name"},
+		},
+		"predicates in relations": {
+			limits:    Limits{MaxPredicates: 1},
+			filterMap: map[string]any{"group": nil, "pets": map[string]any{"$exists": true}},
+			expected:  LimitError{Kind: LimitPredicates, Max: 1, Path: "pets.$exists"},
+		},
+		"subqueries": {
+			limits:    Limits{MaxSubqueries: 2},
+			filterMap: map[string]any{"pets": map[string]any{"name": "Bello", "$all": map[string]any{"age": 3}, "$count": 1}},
+			expected:  LimitError{Kind: LimitSubqueries, Max: 2, Path: "pets.$count"},
+		},
+		"subqueries of aggregates": {
+			limits:    Limits{MaxSubqueries: 1},
+			filterMap: map[string]any{"group": map[string]any{"name": "admins"}, "pets": map[string]any{"$sum": map[string]any{"age": 5}}},
+			expected:  LimitError{Kind: LimitSubqueries, Max: 1, Path: "pets.$sum.age"},
+		},
+		"list size": {
+			limits:    Limits{MaxListSize: 2},
+			filterMap: map[string]any{"name": []any{"Anna", "Jake", "Bello"}},
+			expected:  LimitError{Kind: LimitListSize, Max: 2, Path: "name"},
+		},
+		"list size of operator": {
+			limits:    Limits{MaxListSize: 1},
+			filterMap: map[string]any{"pets": map[string]any{"age": map[string]any{"$between": []int{1, 5}}}},
+			expected:  LimitError{Kind: LimitListSize, Max: 1, Path: "pets.age.$between"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			plugin := New(WithLimits(testData.limits))
+
+			// Act
+			query, err := plugin.AddDeepFilters(database, LimitUser{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, query)
+			assert.ErrorIs(t, err, ErrLimitExceeded)
+
+			var limitError *LimitError
+			require.True(t, errors.As(err, &limitError))
+			assert.Equal(t, testData.expected, *limitError)
+		})
+	}
+}
+
+func TestLimitError_Error_ReturnsExpectedMessage(t *testing.T) {
+	t.Parallel()
+	// Arrange
+	database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+	plugin := New(WithLimits(Limits{MaxSubqueries: 1}), WithAllErrors())
+
+	// Act
+	_, err := plugin.AddDeepFilters(database, LimitUser{}, map[string]any{"group": map[string]any{"name": "admins"}, "pets": map[string]any{"name": "Bello"}})
+
+	// Assert
+	assert.EqualError(t, err, "filter exceeds the maximum number of subqueries of 1 at 'pets': limit exceeded")
+}
+
+func TestDeepGorm_Initialize_EnforcesLimits(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		filterMap map[string]any
+		expected  LimitError
+	}{
+		"list size": {
+			filterMap: map[string]any{"name": []string{"Anna", "Jake", "Bello"}},
+			expected:  LimitError{Kind: LimitListSize, Max: 2, Path: "name"},
+		},
+		"depth": {
+			filterMap: map[string]any{"group": map[string]any{"owner": map[string]any{"name": "Anna"}}},
+			expected:  LimitError{Kind: LimitDepth, Max: 1, Path: "group.owner"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&LimitUser{}, &LimitGroup{}, &LimitOwner{}, &LimitPet{})
+			_ = database.Use(New(WithLimits(Limits{MaxDepth: 1, MaxListSize: 2})))
+
+			// Act
+			err := database.Where(testData.filterMap).Find(&[]LimitUser{}).Error
+
+			// Assert
+			var limitError *LimitError
+			if assert.True(t, errors.As(err, &limitError)) {
+				assert.Equal(t, testData.expected, *limitError)
+			}
+		})
+	}
+}
+
+func TestDeepGorm_Initialize_EnforcesLimitsOnAllConditions(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		query    func(db *gorm.DB) *gorm.DB
+		expected *LimitError
+	}{
+		"within limits": {
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Where(map[string]any{"name": "Jake", "group": map[string]any{"name": "admins"}})
+			},
+		},
+		"subqueries in one map": {
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Where(map[string]any{"group": map[string]any{"name": "admins"}, "pets": map[string]any{"name": "Bello"}})
+			},
+			expected: &LimitError{Kind: LimitSubqueries, Max: 1, Path: "pets"},
+		},
+		"predicates in one map": {
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Where(map[string]any{"name": "Jake", "age": 30, "id": uuid.New()})
+			},
+			expected: &LimitError{Kind: LimitPredicates, Max: 2, Path: "name"},
+		},
+		"predicates in chained calls": {
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Where(map[string]any{"name": "Jake"}).Where(map[string]any{"age": 30}).Where(map[string]any{"id": uuid.New()})
+			},
+			expected: &LimitError{Kind: LimitPredicates, Max: 2, Path: "id"},
+		},
+		"subqueries in chained calls": {
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Where(map[string]any{"group": map[string]any{"name": "admins"}}).Where(map[string]any{"pets": map[string]any{"name": "Bello"}})
+			},
+			expected: &LimitError{Kind: LimitSubqueries, Max: 1, Path: "pets"},
+		},
+		"predicates in or": {
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Where(map[string]any{"name": "Jake"}).Or(map[string]any{"age": 30}).Or(map[string]any{"name": "Anna"})
+			},
+			expected: &LimitError{Kind: LimitPredicates, Max: 2, Path: "name"},
+		},
+		"predicates in not": {
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Where(map[string]any{"name": "Jake"}).Not(map[string]any{"age": 30, "id": uuid.New()})
+			},
+			expected: &LimitError{Kind: LimitPredicates, Max: 2, Path: "id"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&LimitUser{}, &LimitGroup{}, &LimitOwner{}, &LimitPet{})
+			_ = database.Use(New(WithLimits(Limits{MaxSubqueries: 1, MaxPredicates: 2})))
+
+			// Act
+			err := testData.query(database.Model(&LimitUser{})).Find(&[]LimitUser{}).Error
+
+			// Assert
+			if testData.expected == nil {
+				assert.Nil(t, err)
+				return
+			}
+
+			var limitError *LimitError
+			if assert.True(t, errors.As(err, &limitError)) {
+				assert.Equal(t, *testData.expected, *limitError)
+			}
+		})
+	}
+}
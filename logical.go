@@ -0,0 +1,182 @@
+package deepgorm
+
+import (
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// buildLogicalExpression godoc
+// Turns logical combinators into expressions, these may be used at any level of nesting:
+//
+//	map[string]any{
+//		"$or": []map[string]any{
+//			{"group": map[string]any{"name": "X"}},
+//			{"manager": map[string]any{"team": map[string]any{"name": "Y"}}},
+//		},
+//		"$not": map[string]any{
+//			"name": "Jake",
+//		},
+//	}
+//
+// $and and $or expect a non-empty list of filter maps, $not expects a single filter map.
+func (d *DeepGorm) buildLogicalExpression(db *gorm.DB, schemaInfo *schema.Schema, name string, value any) (clause.Expression, error) {
+	switch name {
+	case "$and", "$or":
+		filterObjects, err := toFilterList(value)
+		if err != nil {
+			return nil, newFilterError(schemaInfo, name, value, err)
+		}
+
+		expressions := make([]clause.Expression, 0, len(filterObjects))
+
+		var errs []error
+
+		for index, filterObject := range filterObjects {
+			expression, err := d.buildFilterExpression(db, schemaInfo, filterObject)
+			if err != nil {
+				errs = append(errs, prependPath(err, name, strconv.Itoa(index)))
+				continue
+			}
+
+			if expression != nil {
+				expressions = append(expressions, expression)
+			}
+		}
+
+		if len(errs) > 0 {
+			return nil, joinErrors(errs)
+		}
+
+		if name == "$and" {
+			return clause.And(expressions...), nil
+		}
+
+		return or(expressions...), nil
+
+	case "$not":
+		filterObject, ok := value.(map[string]any)
+		if !ok || len(filterObject) == 0 {
+			return nil, newFilterError(schemaInfo, name, value, fmt.Errorf("expected a non-empty filter map: %w", ErrInvalidOperatorValue))
+		}
+
+		expression, err := d.buildFilterExpression(db, schemaInfo, filterObject)
+		if err != nil {
+			return nil, prependPath(err, name)
+		}
+
+		// Negating a filter without conditions would be NOT (nil)
+		if expression == nil {
+			return nil, nil
+		}
+
+		return negateExpression(expression), nil
+	}
+
+	return nil, newFilterError(schemaInfo, name, value, ErrUnsupportedOperator)
+}
+
+// or combines the expressions using OR, a single OrConditions is seen as 'OR <expression>' by gorm,
+// so we only use it with multiple expressions
+func or(expressions ...clause.Expression) clause.Expression {
+	if len(expressions) < 2 {
+		return clause.And(expressions...)
+	}
+
+	return clause.Or(expressions...)
+}
+
+// negateExpression pushes a negation down to the individual conditions using De Morgan's laws, this way relations
+// get the chance to negate themselves properly. Not using clause.Not here, since it turns NOT (a AND b)
+// into NOT a AND NOT b.
+func negateExpression(expression clause.Expression) clause.Expression {
+	switch expression := expression.(type) {
+	case clause.AndConditions:
+		return or(negateExpressions(expression.Exprs)...)
+
+	case clause.OrConditions:
+		return clause.And(negateExpressions(expression.Exprs)...)
+
+	case clause.NotConditions:
+		if len(expression.Exprs) == 1 {
+			return expression.Exprs[0]
+		}
+
+		return negateExpression(pushDownNot(expression.Exprs, negatesSeparately(expression.Exprs)))
+
+	case relationExpression:
+		return relationExpression{positive: expression.negative, negative: expression.positive}
+	}
+
+	return clause.NotConditions{Exprs: []clause.Expression{expression}}
+}
+
+// negateExpressions negates every expression in the list
+func negateExpressions(expressions []clause.Expression) []clause.Expression {
+	result := make([]clause.Expression, len(expressions))
+	for index, expression := range expressions {
+		result[index] = negateExpression(expression)
+	}
+
+	return result
+}
+
+// negatesSeparately returns true if gorm would build the expressions of a NotConditions as NOT a AND NOT b,
+// this happens if any of them can negate itself, for example with db.Not(map[string]any{...}). Otherwise
+// gorm negates all of them together: NOT (a AND b).
+func negatesSeparately(expressions []clause.Expression) bool {
+	for _, expression := range expressions {
+		if _, ok := expression.(clause.NegationExpressionBuilder); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pushDownNot rewrites the expressions of gorm's NotConditions with the negations pushed down, refer to
+// negatesSeparately for the meaning of negateSeparately.
+func pushDownNot(expressions []clause.Expression, negateSeparately bool) clause.Expression {
+	if negateSeparately {
+		return clause.And(negateExpressions(expressions)...)
+	}
+
+	return or(negateExpressions(expressions)...)
+}
+
+// toFilterList converts the value of $and and $or into a list of filters, the value may come from JSON,
+// so []any is accepted as long as all elements are filter maps. Empty lists and maps are not allowed.
+func toFilterList(value any) ([]map[string]any, error) {
+	var result []map[string]any
+
+	switch value := value.(type) {
+	case []map[string]any:
+		result = value
+
+	case []any:
+		result = make([]map[string]any, 0, len(value))
+		for _, element := range value {
+			filterObject, ok := element.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("expected a list of filter maps: %w", ErrInvalidOperatorValue)
+			}
+
+			result = append(result, filterObject)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("expected a list of filter maps: %w", ErrInvalidOperatorValue)
+	}
+
+	for _, filterObject := range result {
+		if len(filterObject) == 0 {
+			return nil, fmt.Errorf("expected a list of non-empty filter maps: %w", ErrInvalidOperatorValue)
+		}
+	}
+
+	return result, nil
+}
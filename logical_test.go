@@ -0,0 +1,408 @@
+package deepgorm
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ing-bank/gormtestutil"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/clause"
+)
+
+func TestToFilterList_ReturnsExpectedFilters(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		input    any
+		expected []map[string]any
+	}{
+		"typed list": {
+			input:    []map[string]any{{"a": 1}, {"b": 2}},
+			expected: []map[string]any{{"a": 1}, {"b": 2}},
+		},
+		"json list": {
+			input:    []any{map[string]any{"a": 1}, map[string]any{"b": 2}},
+			expected: []map[string]any{{"a": 1}, {"b": 2}},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Act
+			result, err := toFilterList(testData.input)
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, testData.expected, result)
+		})
+	}
+}
+
+func TestToFilterList_ReturnsErrorOnInvalidInput(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		input       any
+		expectedMsg string
+	}{
+		"nil": {
+			input:       nil,
+			expectedMsg: "expected a list of filter maps: invalid operator value",
+		},
+		"single map": {
+			input:       map[string]any{"a": 1},
+			expectedMsg: "expected a list of filter maps: invalid operator value",
+		},
+		"empty list": {
+			input:       []any{},
+			expectedMsg: "expected a list of filter maps: invalid operator value",
+		},
+		"list of strings": {
+			input:       []any{"a", "b"},
+			expectedMsg: "expected a list of filter maps: invalid operator value",
+		},
+		"list with empty map": {
+			input:       []map[string]any{{"a": 1}, {}},
+			expectedMsg: "expected a list of non-empty filter maps: invalid operator value",
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Act
+			result, err := toFilterList(testData.input)
+
+			// Assert
+			assert.Nil(t, result)
+			assert.ErrorIs(t, err, ErrInvalidOperatorValue)
+			assert.EqualError(t, err, testData.expectedMsg)
+		})
+	}
+}
+
+func TestAddDeepFilters_AddsLogicalFilters(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	records := []*ComplexStruct3{
+		{
+			ID:   uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+			Name: "Python",
+			Tags: []*Tag{
+				{
+					ID:    uuid.MustParse("1c83a7c9-e95d-4dba-b858-5eb4e34ebcf2"),
+					Key:   "type",
+					Value: "interpreted",
+					TagValue: &TagValue{
+						ID:    uuid.MustParse("38769e29-e945-451f-a551-3e5811a5d363"),
+						Value: "python-value",
+					},
+				},
+			},
+		},
+		{
+			ID:   uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+			Name: "Go",
+			Tags: []*Tag{
+				{
+					ID:    uuid.MustParse("17983ba8-2d26-4e36-bb6b-6c5a04b6606e"),
+					Key:   "type",
+					Value: "compiled",
+					TagValue: &TagValue{
+						ID:    uuid.MustParse("e75a2f7e-0e1c-4f9c-a8ce-af90f1b64baa"),
+						Value: "go-value",
+					},
+				},
+			},
+		},
+		{
+			ID:   uuid.MustParse("411ed385-c1ca-432d-b577-6d6138450264"),
+			Name: "Rust",
+			Tags: []*Tag{
+				{
+					ID:    uuid.MustParse("451d635a-83f2-47da-b12c-50ec49e45509"),
+					Key:   "type",
+					Value: "compiled",
+					TagValue: &TagValue{
+						ID:    uuid.MustParse("a825637d-9eae-4855-9ee3-a69f1ee65a46"),
+						Value: "rust-value",
+					},
+				},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedNames []string
+	}{
+		"or on simple fields": {
+			filterMap: map[string]any{
+				"$or": []map[string]any{
+					{"name": "Python"},
+					{"name": "Go"},
+				},
+			},
+			expectedNames: []string{"Go", "Python"},
+		},
+		"or on relations from json": {
+			filterMap: map[string]any{
+				"$or": []any{
+					map[string]any{"name": "Python"},
+					map[string]any{
+						"tags": map[string]any{
+							"tag_value": map[string]any{"value": "rust-value"},
+						},
+					},
+				},
+			},
+			expectedNames: []string{"Python", "Rust"},
+		},
+		"or combined with simple field": {
+			filterMap: map[string]any{
+				"name": map[string]any{"$ne": "Go"},
+				"$or": []map[string]any{
+					{"name": "Go"},
+					{"name": "Rust"},
+				},
+			},
+			expectedNames: []string{"Rust"},
+		},
+		"and": {
+			filterMap: map[string]any{
+				"$and": []map[string]any{
+					{"tags": map[string]any{"value": "compiled"}},
+					{"name": "Rust"},
+				},
+			},
+			expectedNames: []string{"Rust"},
+		},
+		"not": {
+			filterMap: map[string]any{
+				"$not": map[string]any{
+					"name": "Rust",
+					"tags": map[string]any{"value": "compiled"},
+				},
+			},
+			expectedNames: []string{"Go", "Python"},
+		},
+		"or inside a relation": {
+			filterMap: map[string]any{
+				"tags": map[string]any{
+					"$or": []map[string]any{
+						{"value": "interpreted"},
+						{"tag_value": map[string]any{"value": "go-value"}},
+					},
+				},
+			},
+			expectedNames: []string{"Go", "Python"},
+		},
+		"not inside a nested relation": {
+			filterMap: map[string]any{
+				"tags": map[string]any{
+					"tag_value": map[string]any{
+						"$not": map[string]any{"value": "go-value"},
+					},
+				},
+			},
+			expectedNames: []string{"Python", "Rust"},
+		},
+		"nested combinators": {
+			filterMap: map[string]any{
+				"$or": []map[string]any{
+					{"name": "Python"},
+					{
+						"$and": []map[string]any{
+							{"tags": map[string]any{"value": "compiled"}},
+							{"$not": map[string]any{"name": "Go"}},
+						},
+					},
+				},
+			},
+			expectedNames: []string{"Python", "Rust"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&ComplexStruct3{}, &Tag{}, &TagValue{})
+
+			database.CreateInBatches(records, len(records))
+
+			// Act
+			query, err := AddDeepFilters(database, ComplexStruct3{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []string
+				res := query.Model(&ComplexStruct3{}).Order("name").Pluck("name", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, testData.expectedNames, result)
+			}
+		})
+	}
+}
+
+func TestAddDeepFilters_ReturnsErrorOnInvalidLogicalFilters(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedError error
+		expectedMsg   string
+	}{
+		"unknown combinator": {
+			filterMap: map[string]any{
+				"$xor": []map[string]any{{"name": "a"}},
+			},
+			expectedError: ErrUnsupportedOperator,
+			expectedMsg:   "failed to add filters for 'complex_struct3.$xor': unsupported operator",
+		},
+		"or with a map": {
+			filterMap: map[string]any{
+				"$or": map[string]any{"name": "a"},
+			},
+			expectedError: ErrInvalidOperatorValue,
+			expectedMsg:   "failed to add filters for 'complex_struct3.$or': expected a list of filter maps: invalid operator value",
+		},
+		"not with a list": {
+			filterMap: map[string]any{
+				"$not": []map[string]any{{"name": "a"}},
+			},
+			expectedError: ErrInvalidOperatorValue,
+			expectedMsg:   "failed to add filters for 'complex_struct3.$not': expected a non-empty filter map: invalid operator value",
+		},
+		"unknown field inside or": {
+			filterMap: map[string]any{
+				"tags": map[string]any{
+					"$or": []map[string]any{{"value": "a"}, {"unknown": "b"}},
+				},
+			},
+			expectedError: ErrFieldDoesNotExist,
+			expectedMsg:   "failed to add filters for 'tags.unknown': field does not exist",
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+
+			// Act
+			query, err := AddDeepFilters(database, ComplexStruct3{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, query)
+			assert.ErrorIs(t, err, testData.expectedError)
+			assert.EqualError(t, err, testData.expectedMsg)
+		})
+	}
+}
+
+func TestNegateExpression_ReturnsExpectedExpression(t *testing.T) {
+	t.Parallel()
+	a := clause.Eq{Column: "a", Value: 1}
+	b := clause.Eq{Column: "b", Value: 2}
+	relation := relationExpression{positive: clause.Expr{SQL: "positive"}, negative: clause.Expr{SQL: "negative"}}
+
+	tests := map[string]struct {
+		input    clause.Expression
+		expected clause.Expression
+	}{
+		"simple": {
+			input:    a,
+			expected: clause.NotConditions{Exprs: []clause.Expression{a}},
+		},
+		"and": {
+			input: clause.And(a, b),
+			expected: clause.OrConditions{Exprs: []clause.Expression{
+				clause.NotConditions{Exprs: []clause.Expression{a}},
+				clause.NotConditions{Exprs: []clause.Expression{b}},
+			}},
+		},
+		"or": {
+			input: clause.Or(a, b),
+			expected: clause.AndConditions{Exprs: []clause.Expression{
+				clause.NotConditions{Exprs: []clause.Expression{a}},
+				clause.NotConditions{Exprs: []clause.Expression{b}},
+			}},
+		},
+		"double negation": {
+			input:    clause.NotConditions{Exprs: []clause.Expression{a}},
+			expected: a,
+		},
+		"relation": {
+			input:    relation,
+			expected: relationExpression{positive: clause.Expr{SQL: "negative"}, negative: clause.Expr{SQL: "positive"}},
+		},
+		"relation in and": {
+			input: clause.And(a, relation),
+			expected: clause.OrConditions{Exprs: []clause.Expression{
+				clause.NotConditions{Exprs: []clause.Expression{a}},
+				relationExpression{positive: clause.Expr{SQL: "negative"}, negative: clause.Expr{SQL: "positive"}},
+			}},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Act
+			result := negateExpression(testData.input)
+
+			// Assert
+			assert.Equal(t, testData.expected, result)
+		})
+	}
+}
+
+func TestPushDownNot_ReturnsExpectedExpression(t *testing.T) {
+	t.Parallel()
+	a := clause.Expr{SQL: "a = 1"}
+	b := clause.Expr{SQL: "b = 2"}
+
+	tests := map[string]struct {
+		negateSeparately bool
+		expected         clause.Expression
+	}{
+		"separately": {
+			negateSeparately: true,
+			expected: clause.AndConditions{Exprs: []clause.Expression{
+				clause.NotConditions{Exprs: []clause.Expression{a}},
+				clause.NotConditions{Exprs: []clause.Expression{b}},
+			}},
+		},
+		"together": {
+			negateSeparately: false,
+			expected: clause.OrConditions{Exprs: []clause.Expression{
+				clause.NotConditions{Exprs: []clause.Expression{a}},
+				clause.NotConditions{Exprs: []clause.Expression{b}},
+			}},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Act
+			result := pushDownNot([]clause.Expression{a, b}, testData.negateSeparately)
+
+			// Assert
+			assert.Equal(t, testData.expected, result)
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package deepgorm
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ing-bank/gormtestutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type NullGroup struct {
+	ID   uuid.UUID
+	Name string
+}
+
+type NullProfile struct {
+	ID            uuid.UUID
+	Bio           string
+	NullAccountID uuid.UUID
+}
+
+type NullSession struct {
+	ID            uuid.UUID
+	Device        string
+	NullAccountID uuid.UUID
+}
+
+type NullRole struct {
+	ID   uuid.UUID
+	Name string
+}
+
+type NullAccount struct {
+	ID        uuid.UUID
+	Name      string
+	DeletedBy *string
+	GroupID   *uuid.UUID
+	Group     *NullGroup
+	Profile   *NullProfile
+	Sessions  []*NullSession
+	Roles     []*NullRole `gorm:"many2many:null_account_roles"`
+}
+
+func TestAddDeepFilters_AddsNullFilters(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	admin := "admin"
+	records := []*NullAccount{
+		{
+			ID:        uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+			Name:      "everything",
+			DeletedBy: &admin,
+			Group:     &NullGroup{ID: uuid.New(), Name: "a"},
+			Profile:   &NullProfile{ID: uuid.New(), Bio: "hello"},
+			Sessions:  []*NullSession{{ID: uuid.New(), Device: "phone"}},
+			Roles:     []*NullRole{{ID: uuid.New(), Name: "reader"}},
+		},
+		{
+			ID:   uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+			Name: "nothing",
+		},
+	}
+
+	tests := map[string]struct {
+		filterMap map[string]any
+		expected  []string
+	}{
+		"column": {
+			filterMap: map[string]any{"deleted_by": nil},
+			expected:  []string{"nothing"},
+		},
+		"column not null": {
+			filterMap: map[string]any{"deleted_by": map[string]any{"$notNull": true}},
+			expected:  []string{"everything"},
+		},
+		"column not null false": {
+			filterMap: map[string]any{"deleted_by": map[string]any{"$notNull": false}},
+			expected:  []string{"nothing"},
+		},
+		"negated column": {
+			filterMap: map[string]any{"$not": map[string]any{"deleted_by": nil}},
+			expected:  []string{"everything"},
+		},
+		"belongs to": {
+			filterMap: map[string]any{"group": nil},
+			expected:  []string{"nothing"},
+		},
+		"belongs to not null": {
+			filterMap: map[string]any{"group": map[string]any{"$notNull": true}},
+			expected:  []string{"everything"},
+		},
+		"negated belongs to": {
+			filterMap: map[string]any{"$not": map[string]any{"group": nil}},
+			expected:  []string{"everything"},
+		},
+		"has one": {
+			filterMap: map[string]any{"profile": nil},
+			expected:  []string{"nothing"},
+		},
+		"has one not null": {
+			filterMap: map[string]any{"profile": map[string]any{"$notNull": true}},
+			expected:  []string{"everything"},
+		},
+		"has many": {
+			filterMap: map[string]any{"sessions": nil},
+			expected:  []string{"nothing"},
+		},
+		"has many not null": {
+			filterMap: map[string]any{"sessions": map[string]any{"$notNull": true}},
+			expected:  []string{"everything"},
+		},
+		"negated has many": {
+			filterMap: map[string]any{"$not": map[string]any{"sessions": nil}},
+			expected:  []string{"everything"},
+		},
+		"many to many": {
+			filterMap: map[string]any{"roles": nil},
+			expected:  []string{"nothing"},
+		},
+		"many to many not null": {
+			filterMap: map[string]any{"roles": map[string]any{"$notNull": true}},
+			expected:  []string{"everything"},
+		},
+		"many to many not null false": {
+			filterMap: map[string]any{"roles": map[string]any{"$notNull": false}},
+			expected:  []string{"nothing"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&NullAccount{}, &NullGroup{}, &NullProfile{}, &NullSession{}, &NullRole{})
+
+			database.CreateInBatches(records, len(records))
+
+			// Act
+			query, err := AddDeepFilters(database, NullAccount{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []string
+				res := query.Model(&NullAccount{}).Pluck("name", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, testData.expected, result)
+			}
+		})
+	}
+}
+
+func TestAddDeepFilters_ReturnsErrorOnInvalidNullFilters(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedError error
+		expectedMsg   string
+	}{
+		"unknown field": {
+			filterMap:     map[string]any{"unknown": nil},
+			expectedError: ErrFieldDoesNotExist,
+			expectedMsg:   "failed to add filters for 'null_accounts.unknown': field does not exist",
+		},
+		"column not null without boolean": {
+			filterMap:     map[string]any{"deleted_by": map[string]any{"$notNull": "yes"}},
+			expectedError: ErrInvalidOperatorValue,
+			expectedMsg:   "failed to add filters for 'null_accounts.deleted_by': '$notNull' expects a boolean: invalid operator value",
+		},
+		"relation not null without boolean": {
+			filterMap:     map[string]any{"group": map[string]any{"$notNull": 1}},
+			expectedError: ErrInvalidOperatorValue,
+			expectedMsg:   "failed to add filters for 'null_accounts.group': '$notNull' expects a boolean: invalid operator value",
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+
+			// Act
+			query, err := AddDeepFilters(database, NullAccount{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, query)
+			assert.ErrorIs(t, err, testData.expectedError)
+			assert.EqualError(t, err, testData.expectedMsg)
+		})
+	}
+}
+
+func TestDeepGorm_Initialize_TriggersNullFiltering(t *testing.T) {
+	t.Parallel()
+	admin := "admin"
+	records := []*NullAccount{
+		{ID: uuid.New(), Name: "grouped", Group: &NullGroup{ID: uuid.New(), Name: "a"}, Sessions: []*NullSession{{ID: uuid.New(), Device: "phone"}}},
+		{ID: uuid.New(), Name: "alone", DeletedBy: &admin},
+	}
+
+	tests := map[string]struct {
+		filterMap map[string]any
+		not       bool
+		expected  []string
+	}{
+		"column": {
+			filterMap: map[string]any{"deleted_by": nil},
+			expected:  []string{"grouped"},
+		},
+		"belongs to": {
+			filterMap: map[string]any{"group": nil},
+			expected:  []string{"alone"},
+		},
+		"has many": {
+			filterMap: map[string]any{"sessions": nil},
+			expected:  []string{"alone"},
+		},
+		"negated has many": {
+			filterMap: map[string]any{"sessions": nil},
+			not:       true,
+			expected:  []string{"grouped"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&NullAccount{}, &NullGroup{}, &NullProfile{}, &NullSession{}, &NullRole{})
+			_ = database.Use(New())
+
+			database.CreateInBatches(records, len(records))
+
+			query := database.Model(&NullAccount{})
+			if testData.not {
+				query = query.Not(testData.filterMap)
+			} else {
+				query = query.Where(testData.filterMap)
+			}
+
+			// Act
+			var result []string
+			res := query.Pluck("name", &result)
+
+			// Assert
+			assert.Nil(t, res.Error)
+			assert.Equal(t, testData.expected, result)
+		})
+	}
+}
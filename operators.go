@@ -0,0 +1,211 @@
+package deepgorm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	// ErrUnsupportedOperator is returned if an operator is unknown or can't be used on the field's type
+	ErrUnsupportedOperator = errors.New("unsupported operator")
+
+	// ErrInvalidOperatorValue is returned if the value given to an operator has the wrong shape
+	ErrInvalidOperatorValue = errors.New("invalid operator value")
+)
+
+// operatorPrefix is used to distinguish operators like '$gt' from field names
+const operatorPrefix = "$"
+
+// operator describes a comparison that can be applied to a simple column
+type operator struct {
+	// dataTypes limits the column types the operator may be used on, nil means all types
+	dataTypes []schema.DataType
+
+	// literal means the value isn't converted to the type of the column, like the pattern of $like
+	literal bool
+
+	// build creates the clause, value has already been validated at this point. The column is usually a
+	// clause.Column, but may also be an expression like a subquery.
+	build func(column any, value any) clause.Expression
+}
+
+// orderedDataTypes are the column types that can be compared using <, >, BETWEEN etc.
+var orderedDataTypes = []schema.DataType{schema.Int, schema.Uint, schema.Float, schema.String, schema.Time}
+
+// operators godoc
+// All the operators that may be used in a filter map, for example:
+//
+//	map[string]any{
+//		"age": map[string]any{
+//			"$gte": 18,
+//			"$lt":  65,
+//		},
+//		"deleted_at": map[string]any{
+//			"$notNull": true,
+//		},
+//	}
+var operators = map[string]operator{
+	"$ne": {
+		build: func(column any, value any) clause.Expression {
+			return clause.Neq{Column: column, Value: value}
+		},
+	},
+	"$gt": {
+		dataTypes: orderedDataTypes,
+		build: func(column any, value any) clause.Expression {
+			return clause.Gt{Column: column, Value: value}
+		},
+	},
+	"$gte": {
+		dataTypes: orderedDataTypes,
+		build: func(column any, value any) clause.Expression {
+			return clause.Gte{Column: column, Value: value}
+		},
+	},
+	"$lt": {
+		dataTypes: orderedDataTypes,
+		build: func(column any, value any) clause.Expression {
+			return clause.Lt{Column: column, Value: value}
+		},
+	},
+	"$lte": {
+		dataTypes: orderedDataTypes,
+		build: func(column any, value any) clause.Expression {
+			return clause.Lte{Column: column, Value: value}
+		},
+	},
+	"$like": {
+		dataTypes: []schema.DataType{schema.String},
+		literal:   true,
+		build: func(column any, value any) clause.Expression {
+			return clause.Like{Column: column, Value: value}
+		},
+	},
+	"$notNull": {
+		literal: true,
+		build: func(column any, value any) clause.Expression {
+			if value.(bool) {
+				return clause.Neq{Column: column, Value: nil}
+			}
+
+			return clause.Eq{Column: column, Value: nil}
+		},
+	},
+	"$between": {
+		dataTypes: orderedDataTypes,
+		build: func(column any, value any) clause.Expression {
+			bounds := reflect.ValueOf(value)
+			return clause.Expr{SQL: "? BETWEEN ? AND ?", Vars: []any{column, bounds.Index(0).Interface(), bounds.Index(1).Interface()}}
+		},
+	},
+}
+
+// isOperatorMap returns true if all the keys in the map are operators, like {"$gt": 5, "$lt": 10}. Empty maps
+// are not considered operator maps.
+func isOperatorMap(filter map[string]any) bool {
+	if len(filter) == 0 {
+		return false
+	}
+
+	for key := range filter {
+		if !strings.HasPrefix(key, operatorPrefix) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// validateOperatorValue makes sure the given value has a shape the operator can work with
+func validateOperatorValue(name string, value any) error {
+	reflectValue := reflect.ValueOf(value)
+
+	switch name {
+	case "$between":
+		if kind := reflectValue.Kind(); kind != reflect.Slice && kind != reflect.Array {
+			return fmt.Errorf("'%s' expects a list of 2 values: %w", name, ErrInvalidOperatorValue)
+		}
+
+		if reflectValue.Len() != 2 {
+			return fmt.Errorf("'%s' expects a list of 2 values, got %d: %w", name, reflectValue.Len(), ErrInvalidOperatorValue)
+		}
+
+	case "$like":
+		if reflectValue.Kind() != reflect.String {
+			return fmt.Errorf("'%s' expects a string: %w", name, ErrInvalidOperatorValue)
+		}
+
+	case "$notNull":
+		if reflectValue.Kind() != reflect.Bool {
+			return fmt.Errorf("'%s' expects a boolean: %w", name, ErrInvalidOperatorValue)
+		}
+
+	default:
+		switch reflectValue.Kind() {
+		case reflect.Map, reflect.Slice, reflect.Invalid:
+			return fmt.Errorf("'%s' expects a single value: %w", name, ErrInvalidOperatorValue)
+		}
+	}
+
+	return nil
+}
+
+// coerceOperatorValue validates the value of the operator and converts it using coerce, unless the operator's value
+// is literal like the pattern of $like
+func coerceOperatorValue(coerce coercer, name string, value any) (any, error) {
+	if err := validateOperatorValue(name, value); err != nil {
+		return nil, err
+	}
+
+	if coerce == nil || operators[name].literal {
+		return value, nil
+	}
+
+	return coerce(value)
+}
+
+// buildOperatorExpression turns a map of operators into a single expression on the given field, multiple
+// operators are combined using AND.
+func buildOperatorExpression(schemaInfo *schema.Schema, field *schema.Field, filter map[string]any) (clause.Expression, error) {
+	expression, err := compare(clause.Column{Table: schemaInfo.Table, Name: field.DBName}, field.DataType, coercerOf(field), filter)
+	if err != nil {
+		return nil, newFilterError(schemaInfo, field.DBName, filter, err)
+	}
+
+	return expression, nil
+}
+
+// compare applies a map of operators to the column, which is of the given data type. The values are converted using
+// coerce if it's not nil, refer to coercerOf.
+func compare(column any, dataType schema.DataType, coerce coercer, filter map[string]any) (clause.Expression, error) {
+	// Sort the operators to get a predictable query
+	names := sortedKeys(filter)
+
+	expressions := make([]clause.Expression, 0, len(names))
+
+	for _, name := range names {
+		op, ok := operators[name]
+		if !ok {
+			return nil, fmt.Errorf("'%s': %w", name, ErrUnsupportedOperator)
+		}
+
+		if op.dataTypes != nil && !slices.Contains(op.dataTypes, dataType) {
+			return nil, fmt.Errorf("'%s' can't be used on type '%s': %w", name, dataType, ErrUnsupportedOperator)
+		}
+
+		value, err := coerceOperatorValue(coerce, name, filter[name])
+		if err != nil {
+			return nil, err
+		}
+
+		expressions = append(expressions, op.build(column, value))
+	}
+
+	return clause.And(expressions...), nil
+}
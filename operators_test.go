@@ -0,0 +1,191 @@
+package deepgorm
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ing-bank/gormtestutil"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+type OperatorStruct struct {
+	ID        int
+	Name      string
+	Age       int
+	Active    bool
+	CreatedAt time.Time
+}
+
+func TestIsOperatorMap_ReturnsExpectedResult(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		input    map[string]any
+		expected bool
+	}{
+		"nil": {
+			input:    nil,
+			expected: false,
+		},
+		"empty": {
+			input:    map[string]any{},
+			expected: false,
+		},
+		"only operators": {
+			input:    map[string]any{"$gt": 1, "$lt": 5},
+			expected: true,
+		},
+		"only fields": {
+			input:    map[string]any{"name": "abc"},
+			expected: false,
+		},
+		"mixed": {
+			input:    map[string]any{"$gt": 1, "name": "abc"},
+			expected: false,
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Act
+			result := isOperatorMap(testData.input)
+
+			// Assert
+			assert.Equal(t, testData.expected, result)
+		})
+	}
+}
+
+func TestBuildOperatorExpression_ReturnsExpectedExpression(t *testing.T) {
+	t.Parallel()
+	column := clause.Column{Table: "operator_structs", Name: "age"}
+
+	tests := map[string]struct {
+		filter   map[string]any
+		expected clause.Expression
+	}{
+		"ne": {
+			filter:   map[string]any{"$ne": 5},
+			expected: clause.Neq{Column: column, Value: 5},
+		},
+		"gt": {
+			filter:   map[string]any{"$gt": 5},
+			expected: clause.Gt{Column: column, Value: 5},
+		},
+		"gte": {
+			filter:   map[string]any{"$gte": 5},
+			expected: clause.Gte{Column: column, Value: 5},
+		},
+		"lt": {
+			filter:   map[string]any{"$lt": 5},
+			expected: clause.Lt{Column: column, Value: 5},
+		},
+		"lte": {
+			filter:   map[string]any{"$lte": 5},
+			expected: clause.Lte{Column: column, Value: 5},
+		},
+		"between": {
+			filter:   map[string]any{"$between": []int{1, 5}},
+			expected: clause.Expr{SQL: "? BETWEEN ? AND ?", Vars: []any{column, 1, 5}},
+		},
+		"multiple": {
+			filter: map[string]any{"$lt": 10, "$gt": 5},
+			expected: clause.AndConditions{Exprs: []clause.Expression{
+				clause.Gt{Column: column, Value: 5},
+				clause.Lt{Column: column, Value: 10},
+			}},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			naming := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name())).NamingStrategy
+			schemaInfo, _ := schema.Parse(&OperatorStruct{}, &sync.Map{}, naming)
+
+			// Act
+			result, err := buildOperatorExpression(schemaInfo, schemaInfo.FieldsByDBName["age"], testData.filter)
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, testData.expected, result)
+		})
+	}
+}
+
+func TestBuildOperatorExpression_ReturnsErrorOnInvalidOperators(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		field         string
+		filter        map[string]any
+		expectedError error
+		expectedMsg   string
+	}{
+		"unknown operator": {
+			field:         "age",
+			filter:        map[string]any{"$regex": "abc"},
+			expectedError: ErrUnsupportedOperator,
+			expectedMsg:   "failed to add filters for 'operator_structs.age': '$regex': unsupported operator",
+		},
+		"like on int": {
+			field:         "age",
+			filter:        map[string]any{"$like": "1%"},
+			expectedError: ErrUnsupportedOperator,
+			expectedMsg:   "failed to add filters for 'operator_structs.age': '$like' can't be used on type 'int': unsupported operator",
+		},
+		"gt on bool": {
+			field:         "active",
+			filter:        map[string]any{"$gt": true},
+			expectedError: ErrUnsupportedOperator,
+			expectedMsg:   "failed to add filters for 'operator_structs.active': '$gt' can't be used on type 'bool': unsupported operator",
+		},
+		"like with number": {
+			field:         "name",
+			filter:        map[string]any{"$like": 5},
+			expectedError: ErrInvalidOperatorValue,
+			expectedMsg:   "failed to add filters for 'operator_structs.name': '$like' expects a string: invalid operator value",
+		},
+		"between with single value": {
+			field:         "created_at",
+			filter:        map[string]any{"$between": time.Now()},
+			expectedError: ErrInvalidOperatorValue,
+			expectedMsg:   "failed to add filters for 'operator_structs.created_at': '$between' expects a list of 2 values: invalid operator value",
+		},
+		"between with 3 values": {
+			field:         "age",
+			filter:        map[string]any{"$between": []int{1, 2, 3}},
+			expectedError: ErrInvalidOperatorValue,
+			expectedMsg:   "failed to add filters for 'operator_structs.age': '$between' expects a list of 2 values, got 3: invalid operator value",
+		},
+		"gt with list": {
+			field:         "age",
+			filter:        map[string]any{"$gt": []int{1, 2}},
+			expectedError: ErrInvalidOperatorValue,
+			expectedMsg:   "failed to add filters for 'operator_structs.age': '$gt' expects a single value: invalid operator value",
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			naming := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name())).NamingStrategy
+			schemaInfo, _ := schema.Parse(&OperatorStruct{}, &sync.Map{}, naming)
+
+			// Act
+			result, err := buildOperatorExpression(schemaInfo, schemaInfo.FieldsByDBName[testData.field], testData.filter)
+
+			// Assert
+			assert.Nil(t, result)
+			assert.ErrorIs(t, err, testData.expectedError)
+			assert.EqualError(t, err, testData.expectedMsg)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package deepgorm
+
+import "reflect"
+
+// Option configures an instance created by New
+type Option func(*DeepGorm)
+
+// WithRowValueDialects sets the databases that support row values like (a, b) IN (SELECT x, y ...), relations with
+// composite keys use correlated EXISTS-subqueries on all other databases. Defaults to sqlite, postgres and mysql.
+func WithRowValueDialects(dialects ...string) Option {
+	return func(d *DeepGorm) {
+		d.rowValueDialects = make(map[string]bool, len(dialects))
+		for _, dialect := range dialects {
+			d.rowValueDialects[dialect] = true
+		}
+	}
+}
+
+// WithModels registers the models when the plugin is initialized, db.Use returns an error if any of their
+// relations can't be used in filters. Refer to DeepGorm.Register.
+func WithModels(models ...any) Option {
+	return func(d *DeepGorm) {
+		d.models = append(d.models, models...)
+	}
+}
+
+// WithAllErrors makes AddDeepFilters and the plugin return all the problems in a filter instead of only the first one.
+// The errors are combined using errors.Join in the order of the (sorted) keys of the filter, every one of them
+// can be found using errors.As or by unwrapping them.
+func WithAllErrors() Option {
+	return func(d *DeepGorm) {
+		d.allErrors = true
+	}
+}
+
+// WithKeys sets the keys that may be used in filters at every level of the filter, for example the names in json tags
+// using WithKeys(KeyByJSONTag). A field may be used by every key the resolvers return, other keys are unknown fields.
+// If fields share a key, the earlier resolver takes precedence. Defaults to KeyByColumn.
+func WithKeys(resolvers ...KeyResolver) Option {
+	return func(d *DeepGorm) {
+		d.keyResolvers = resolvers
+	}
+}
+
+// WithPolicy limits the fields that may be used in filters on the model, refer to Policy. Filters that use other fields
+// return an error matching ErrFieldNotFilterable. Policies of the same model are combined.
+func WithPolicy(model any, policy Policy) Option {
+	return func(d *DeepGorm) {
+		modelType := ensureConcrete(ensureNotASlice(reflect.TypeOf(model)))
+
+		current := d.policies[modelType]
+		current.Allow = append(current.Allow, policy.Allow...)
+		current.Deny = append(current.Deny, policy.Deny...)
+
+		d.policies[modelType] = current
+	}
+}
+
+// WithLimits limits the size of the filters, refer to Limits. Filters that exceed one of the limits return a LimitError.
+func WithLimits(limits Limits) Option {
+	return func(d *DeepGorm) {
+		d.limits = limits
+	}
+}
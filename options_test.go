@@ -0,0 +1,135 @@
+package deepgorm
+
+import (
+	"testing"
+
+	"github.com/ing-bank/gormtestutil"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestNew_ReturnsInstancesWithOwnCaches(t *testing.T) {
+	t.Parallel()
+	// Arrange
+	database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+	_ = database.AutoMigrate(&ObjectA{}, &ObjectB{})
+
+	first := New()
+	second := New()
+
+	// Act
+	_, err := first.AddDeepFilters(database, ObjectA{}, map[string]any{"object_bs": map[string]any{"name": "a"}})
+
+	// Assert
+	assert.Nil(t, err)
+
+	firstCount, secondCount := 0, 0
+	first.relationCache.Range(func(cacheKey, map[string]*nestedType) bool { firstCount++; return true })
+	second.relationCache.Range(func(cacheKey, map[string]*nestedType) bool { secondCount++; return true })
+
+	assert.NotZero(t, firstCount)
+	assert.Zero(t, secondCount)
+}
+
+func TestWithRowValueDialects_SetsDialects(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		options  []Option
+		expected bool
+	}{
+		"default": {
+			expected: true,
+		},
+		"other dialect": {
+			options:  []Option{WithRowValueDialects("postgres")},
+			expected: false,
+		},
+		"same dialect": {
+			options:  []Option{WithRowValueDialects("postgres", "sqlite")},
+			expected: true,
+		},
+		"none": {
+			options:  []Option{WithRowValueDialects()},
+			expected: false,
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			plugin := New(testData.options...)
+
+			// Act
+			result := plugin.useRowValues(database, []string{"tenant_id", "id"})
+
+			// Assert
+			assert.Equal(t, testData.expected, result)
+		})
+	}
+}
+
+func TestDeepGorm_AddDeepFilters_UsesConfiguration(t *testing.T) {
+	t.Parallel()
+	// Arrange
+	records := []*CompositeOrder{
+		{TenantID: "a", ID: "1", Name: "first", Lines: []*CompositeLine{{ID: "1", Product: "apple"}}},
+		{TenantID: "b", ID: "1", Name: "second", Lines: []*CompositeLine{{ID: "1", Product: "pear"}}},
+	}
+
+	database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+	_ = database.AutoMigrate(&CompositeOrder{}, &CompositeLine{}, &CompositeTag{})
+
+	database.CreateInBatches(records, len(records))
+
+	plugin := New(WithRowValueDialects())
+
+	// Act
+	query, err := plugin.AddDeepFilters(database, CompositeOrder{}, map[string]any{"lines": map[string]any{"product": "pear"}})
+
+	// Assert
+	assert.Nil(t, err)
+
+	if assert.NotNil(t, query) {
+		statement := query.Session(&gorm.Session{DryRun: true}).Find(&[]*CompositeOrder{}).Statement
+		assert.Contains(t, statement.SQL.String(), "EXISTS")
+
+		var result []string
+		res := query.Model(&CompositeOrder{}).Pluck("name", &result)
+
+		// Handle error
+		assert.Nil(t, res.Error)
+
+		assert.Equal(t, []string{"second"}, result)
+	}
+}
+
+func TestDeepGorm_Initialize_UsesConfiguration(t *testing.T) {
+	t.Parallel()
+	// Arrange
+	records := []*CompositeOrder{
+		{TenantID: "a", ID: "1", Name: "first", Lines: []*CompositeLine{{ID: "1", Product: "apple"}}},
+		{TenantID: "b", ID: "1", Name: "second", Lines: []*CompositeLine{{ID: "1", Product: "pear"}}},
+	}
+
+	database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+	_ = database.AutoMigrate(&CompositeOrder{}, &CompositeLine{}, &CompositeTag{})
+
+	database.CreateInBatches(records, len(records))
+
+	// Act
+	err := database.Use(New(WithRowValueDialects()))
+
+	// Assert
+	assert.Nil(t, err)
+
+	var result []string
+	res := database.Model(&CompositeOrder{}).Where(map[string]any{"lines": map[string]any{"product": "apple"}}).Pluck("name", &result)
+
+	// Handle error
+	assert.Nil(t, res.Error)
+
+	assert.Equal(t, []string{"first"}, result)
+}
@@ -1,62 +1,323 @@
 package deepgorm
 
 import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/survivorbat/go-tsyncmap"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
-	"reflect"
+	"gorm.io/gorm/schema"
 )
 
 // Compile-time interface check
-var _ gorm.Plugin = new(deepGorm)
+var _ gorm.Plugin = new(DeepGorm)
+
+// defaultDeepGorm is used by the package-level functions like AddDeepFilters
+var defaultDeepGorm = New()
 
-// New creates a new instance of the plugin that can be registered in gorm.
-func New() gorm.Plugin {
-	return &deepGorm{}
+// New creates a new instance of the plugin that can be registered in gorm. Every instance has its own
+// configuration and caches, so instances for different databases don't influence each other.
+func New(opts ...Option) *DeepGorm {
+	result := &DeepGorm{
+		rowValueDialects: defaultRowValueDialects,
+		keyResolvers:     []KeyResolver{KeyByColumn},
+		policies:         map[reflect.Type]Policy{},
+	}
+
+	for _, opt := range opts {
+		opt(result)
+	}
+
+	return result
 }
 
-type deepGorm struct {
+// DeepGorm is the plugin, it can also be used to add deep filters to queries directly using AddDeepFilters
+type DeepGorm struct {
+	// Cache mechanism for reflecting database structs, reflection is slow, so we
+	// cache results for quick lookups.
+
+	// relationCache map[cacheKey]map[string]*nestedType{}
+	relationCache tsyncmap.Map[cacheKey, map[string]*nestedType]
+
+	// schemaCache is for gorm's schema.Parse, which only uses the type as a key. That's why
+	// there's a cache for every naming strategy, otherwise they would share table names.
+	schemaCache tsyncmap.Map[any, *sync.Map]
+
+	// keyCache map[cacheKey]map[string]string{}, refer to getKeysOfType
+	keyCache tsyncmap.Map[cacheKey, map[string]string]
+
+	// embeddedCache map[cacheKey]map[string]*embeddedType{}, refer to getEmbeddedFieldsOfType
+	embeddedCache tsyncmap.Map[cacheKey, map[string]*embeddedType]
+
+	// rowValueDialects are the databases that use row values for composite keys, refer to WithRowValueDialects
+	rowValueDialects map[string]bool
+
+	// models are registered when the plugin is initialized, refer to WithModels
+	models []any
+
+	// allErrors returns all the problems in a filter instead of the first one, refer to WithAllErrors
+	allErrors bool
+
+	// keyResolvers determine the keys that may be used in filters, refer to WithKeys
+	keyResolvers []KeyResolver
+
+	// policies limit the fields that may be used in filters on a model, refer to WithPolicy
+	policies map[reflect.Type]Policy
+
+	// limits limit the size of the filters, refer to WithLimits
+	limits Limits
 }
 
-func (d *deepGorm) Name() string {
+func (d *DeepGorm) Name() string {
 	return "deepgorm"
 }
 
-func (d *deepGorm) Initialize(db *gorm.DB) error {
-	return db.Callback().Query().Before("gorm:query").Register("deepgorm:query", queryCallback)
+func (d *DeepGorm) Initialize(db *gorm.DB) error {
+	if err := d.Register(db, d.models...); err != nil {
+		return err
+	}
+
+	return db.Callback().Query().Before("gorm:query").Register("deepgorm:query", d.queryCallback)
 }
 
-func queryCallback(db *gorm.DB) {
+func (d *DeepGorm) queryCallback(db *gorm.DB) {
 	exp, ok := db.Statement.Clauses["WHERE"].Expression.(clause.Where)
 	if !ok {
 		return
 	}
 
-	createDeepFilterRecursively(exp.Exprs, db)
+	if !d.checkLimits(db, exp.Exprs) {
+		return
+	}
+
+	d.createDeepFilterRecursively(exp.Exprs, db)
+}
+
+// checkLimits checks the limits on all the conditions of the query at once, including the ones of chained Where
+// calls and plain columns, refer to Limits. Returns false if an error was added to the db.
+func (d *DeepGorm) checkLimits(db *gorm.DB, exprs []clause.Expression) bool {
+	if d.limits == (Limits{}) || db.Statement.Model == nil {
+		return true
+	}
+
+	inputObject := reflect.New(ensureNotASlice(reflect.TypeOf(db.Statement.Model))).Interface()
+
+	// Fields that may not be used are reported by the deep filters
+	var limitError *LimitError
+	if err := d.checkFilters(db, inputObject, d.statementFilters(db, exprs)); errors.As(err, &limitError) {
+		_ = db.AddError(limitError)
+		return false
+	}
+
+	return true
+}
+
+// statementFilters turns the conditions of a query back into filter maps. The plugin builds every condition on its own,
+// also in db.Or and db.Not, so those are flattened. Conditions that don't refer to a column like
+// db.Where("age > ?", 18) are left out.
+func (d *DeepGorm) statementFilters(db *gorm.DB, exprs []clause.Expression) []map[string]any {
+	var result []map[string]any
+
+	for _, cond := range exprs {
+		switch cond := cond.(type) {
+		case clause.AndConditions:
+			result = append(result, d.statementFilters(db, cond.Exprs)...)
+		case clause.OrConditions:
+			result = append(result, d.statementFilters(db, cond.Exprs)...)
+		case clause.NotConditions:
+			result = append(result, d.statementFilters(db, cond.Exprs)...)
+		case clause.Eq:
+			if key, ok := d.statementKey(db, cond.Column); ok {
+				result = append(result, map[string]any{key: cond.Value})
+			}
+		case clause.IN:
+			if key, ok := d.statementKey(db, cond.Column); ok {
+				result = append(result, map[string]any{key: cond.Values})
+			}
+		}
+	}
+
+	return result
 }
 
-func createDeepFilterRecursively(exprs []clause.Expression, db *gorm.DB) {
+func (d *DeepGorm) createDeepFilterRecursively(exprs []clause.Expression, db *gorm.DB) {
 	for index, cond := range exprs {
 		switch cond := cond.(type) {
 		case clause.AndConditions:
-			createDeepFilterRecursively(exprs[index].(clause.AndConditions).Exprs, db)
+			d.createDeepFilterRecursively(exprs[index].(clause.AndConditions).Exprs, db)
 		case clause.OrConditions:
-			createDeepFilterRecursively(exprs[index].(clause.OrConditions).Exprs, db)
+			d.createDeepFilterRecursively(exprs[index].(clause.OrConditions).Exprs, db)
+		case clause.NotConditions:
+			// Must be determined before the deep filters replace the original expressions
+			negateSeparately := negatesSeparately(cond.Exprs)
+
+			d.createDeepFilterRecursively(cond.Exprs, db)
+
+			// Push the negation down so relations can negate themselves, refer to relationExpression
+			exprs[index] = pushDownNot(cond.Exprs, negateSeparately)
 		case clause.Eq:
+			key, ok := d.statementKey(db, cond.Column)
+			if !ok {
+				continue
+			}
+
 			switch value := cond.Value.(type) {
 			case map[string]any:
-				concreteType := ensureNotASlice(reflect.TypeOf(db.Statement.Model))
-				inputObject := ensureConcrete(reflect.New(concreteType)).Interface()
+				if !d.replaceWithDeepFilter(exprs, index, db, map[string]any{key: value}) {
+					return
+				}
+			default:
+				// Relations like {"group": nil} and aliases like {"groupName": "X"}, other columns are left to gorm
+				if d.needsDeepFilter(db, key) {
+					if !d.replaceWithDeepFilter(exprs, index, db, map[string]any{key: value}) {
+						return
+					}
+
+					continue
+				}
+
+				coerced, ok := d.coerceColumn(db, key, value)
+				if !ok {
+					return
+				}
 
-				applied, err := AddDeepFilters(db.Session(&gorm.Session{NewDB: true}), inputObject, map[string]any{cond.Column.(string): value})
+				exprs[index] = clause.Eq{Column: cond.Column, Value: coerced}
+			}
+		case clause.IN:
+			key, ok := d.statementKey(db, cond.Column)
+			if !ok {
+				continue
+			}
 
-				if err != nil {
-					_ = db.AddError(err)
+			// Gorm turns lists like {"$or": []map[string]any{...}} into an IN-clause
+			if strings.HasPrefix(key, operatorPrefix) || d.needsDeepFilter(db, key) {
+				if !d.replaceWithDeepFilter(exprs, index, db, map[string]any{key: cond.Values}) {
 					return
 				}
 
-				// Replace the map filter with the newly created deep-filter
-				exprs[index] = applied.Statement.Clauses["WHERE"].Expression.(clause.Where).Exprs[0]
+				continue
+			}
+
+			coerced, ok := d.coerceColumn(db, key, cond.Values)
+			if !ok {
+				return
+			}
+
+			if values, ok := coerced.([]any); ok {
+				exprs[index] = clause.IN{Column: cond.Column, Values: values}
 			}
 		}
 	}
 }
+
+// statementKey returns the key of the column of a condition, like the key in a filter. Gorm uses strings for the keys
+// of maps, which may contain the table like "users.name", and clause.Column for the fields of structs. The table is
+// removed if it's the table of the model, other tables are left to gorm. Returns false for columns that aren't strings
+// or columns.
+func (d *DeepGorm) statementKey(db *gorm.DB, column any) (string, bool) {
+	switch column := column.(type) {
+	case string:
+		table, name, qualified := strings.Cut(column, ".")
+		if !qualified {
+			return column, true
+		}
+
+		if schemaInfo, ok := d.modelSchema(db); ok && table == schemaInfo.Table {
+			return name, true
+		}
+
+		return column, true
+	case clause.Column:
+		if column.Raw {
+			return "", false
+		}
+
+		if column.Table == "" || column.Table == clause.CurrentTable {
+			return column.Name, true
+		}
+
+		if schemaInfo, ok := d.modelSchema(db); ok && column.Table == schemaInfo.Table {
+			return column.Name, true
+		}
+	}
+
+	return "", false
+}
+
+// modelSchema returns the schema of the model of the query, or false if the query has no model that can be parsed
+func (d *DeepGorm) modelSchema(db *gorm.DB) (*schema.Schema, bool) {
+	if db.Statement.Model == nil {
+		return nil, false
+	}
+
+	schemaInfo, err := d.parseSchema(db, reflect.New(ensureNotASlice(reflect.TypeOf(db.Statement.Model))).Interface())
+	if err != nil {
+		return nil, false
+	}
+
+	return schemaInfo, true
+}
+
+// coerceColumn converts the value of a column that's left to gorm to the type of the field, like the deep filters do,
+// refer to coerceValue. Keys that aren't columns of the model are left alone. Returns false if an error was added to
+// the db.
+func (d *DeepGorm) coerceColumn(db *gorm.DB, column string, value any) (any, bool) {
+	schemaInfo, ok := d.modelSchema(db)
+	if !ok {
+		return value, true
+	}
+
+	field, ok := schemaInfo.FieldsByDBName[column]
+	if !ok {
+		return value, true
+	}
+
+	coerced, err := coerceValue(field, value)
+	if err != nil {
+		_ = db.AddError(newFilterError(schemaInfo, column, value, err))
+		return nil, false
+	}
+
+	return coerced, true
+}
+
+// needsDeepFilter returns whether the key is a relation of the model of the query or an alias of a column, refer to
+// WithKeys. Gorm can't handle either of them. Columns that may not be used are checked as well, refer to Policy.
+func (d *DeepGorm) needsDeepFilter(db *gorm.DB, key string) bool {
+	schemaInfo, ok := d.modelSchema(db)
+	if !ok {
+		return false
+	}
+
+	if _, ok := d.getDatabaseFieldsOfType(db.NamingStrategy, schemaInfo)[key]; ok {
+		return true
+	}
+
+	column, ok := d.getKeysOfType(db.NamingStrategy, schemaInfo)[key]
+	if !ok {
+		return false
+	}
+
+	return column != key || d.isHiddenColumn(db.NamingStrategy, schemaInfo, column) || !d.policyOf(schemaInfo).allows(column)
+}
+
+// replaceWithDeepFilter replaces the expression at the given index with a deep filter, returns false if an error
+// was added to the db
+func (d *DeepGorm) replaceWithDeepFilter(exprs []clause.Expression, index int, db *gorm.DB, filter map[string]any) bool {
+	concreteType := ensureNotASlice(reflect.TypeOf(db.Statement.Model))
+	inputObject := ensureConcrete(reflect.New(concreteType)).Interface()
+
+	applied, err := d.AddDeepFilters(db.Session(&gorm.Session{NewDB: true}), inputObject, filter)
+
+	if err != nil {
+		_ = db.AddError(err)
+		return false
+	}
+
+	// Replace the map filter with the newly created deep-filter
+	exprs[index] = applied.Statement.Clauses["WHERE"].Expression.(clause.Where).Exprs[0]
+	return true
+}
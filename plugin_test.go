@@ -6,6 +6,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/ing-bank/gormtestutil"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
@@ -145,6 +146,45 @@ func TestDeepGorm_Initialize_TriggersFilteringCorrectly(t *testing.T) {
 				},
 			},
 		},
+		"operator filter": {
+			filter: map[string]any{
+				"object_bs": map[string]any{
+					"name": map[string]any{"$like": "a%"},
+				},
+			},
+			existing: []ObjectA{
+				{
+					ID: uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481688"),
+					ObjectBs: []ObjectB{
+						{
+							ID:   uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481688"),
+							Name: "def",
+						},
+					},
+				},
+				{
+					ID: uuid.MustParse("3415d786-bc03-4543-aa3c-5ec9e55aa460"),
+					ObjectBs: []ObjectB{
+						{
+							ID:   uuid.MustParse("83aaf47d-a167-4a49-8b7c-3516ced56e8a"),
+							Name: "abc",
+						},
+					},
+				},
+			},
+			expected: []ObjectA{
+				{
+					ID: uuid.MustParse("3415d786-bc03-4543-aa3c-5ec9e55aa460"),
+					ObjectBs: []ObjectB{
+						{
+							ID:        uuid.MustParse("83aaf47d-a167-4a49-8b7c-3516ced56e8a"),
+							Name:      "abc",
+							ObjectAID: uuid.MustParse("3415d786-bc03-4543-aa3c-5ec9e55aa460"),
+						},
+					},
+				},
+			},
+		},
 		"multi filter": {
 			filter: map[string]any{
 				"name": "ghi",
@@ -319,3 +359,177 @@ func TestDeepGorm_Initialize_TriggersFilteringCorrectlyWithOrQuery(t *testing.T)
 
 	assert.Equal(t, expected, actual)
 }
+
+func TestDeepGorm_Initialize_TriggersLogicalFiltering(t *testing.T) {
+	t.Parallel()
+	existing := []ObjectA{
+		{
+			ID:   uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481688"),
+			Name: "ghi",
+			ObjectBs: []ObjectB{
+				{ID: uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481688"), Name: "def"},
+			},
+		},
+		{
+			ID:   uuid.MustParse("3415d786-bc03-4543-aa3c-5ec9e55aa460"),
+			Name: "nope",
+			ObjectBs: []ObjectB{
+				{ID: uuid.MustParse("83aaf47d-a167-4a49-8b7c-3516ced56e8a"), Name: "abc"},
+			},
+		},
+		{
+			ID:   uuid.MustParse("383e9a9b-ef95-421d-a89e-60f0344ee29d"),
+			Name: "Maybe",
+			ObjectBs: []ObjectB{
+				{ID: uuid.MustParse("3b35e207-c544-424e-b029-be31d5fe8bad"), Name: "cba"},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		filter        map[string]any
+		expectedNames []string
+	}{
+		"or": {
+			filter: map[string]any{
+				"$or": []map[string]any{
+					{"object_bs": map[string]any{"name": "def"}},
+					{"name": "Maybe"},
+				},
+			},
+			expectedNames: []string{"Maybe", "ghi"},
+		},
+		"not": {
+			filter: map[string]any{
+				"$not": map[string]any{
+					"object_bs": map[string]any{"name": "def"},
+				},
+			},
+			expectedNames: []string{"Maybe", "nope"},
+		},
+		"and inside relation": {
+			filter: map[string]any{
+				"object_bs": map[string]any{
+					"$and": []any{
+						map[string]any{"name": map[string]any{"$like": "%b%"}},
+						map[string]any{"name": map[string]any{"$like": "a%"}},
+					},
+				},
+			},
+			expectedNames: []string{"nope"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			db := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = db.AutoMigrate(&ObjectA{}, &ObjectB{})
+
+			if err := db.CreateInBatches(existing, 10).Error; err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+
+			// Act
+			err := db.Use(New())
+
+			// Assert
+			assert.Nil(t, err)
+
+			var actual []string
+			err = db.Model(&ObjectA{}).Where(testData.filter).Order("name").Pluck("name", &actual).Error
+			assert.Nil(t, err)
+
+			assert.Equal(t, testData.expectedNames, actual)
+		})
+	}
+}
+
+func TestDeepGorm_Initialize_TriggersNegatedFiltering(t *testing.T) {
+	t.Parallel()
+	existing := []*NullableMember{
+		{
+			ID:   uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481688"),
+			Name: "admin",
+			NullableGroup: &NullableGroup{
+				ID:   uuid.MustParse("83aaf47d-a167-4a49-8b7c-3516ced56e8a"),
+				Name: "admins",
+			},
+		},
+		{
+			ID:   uuid.MustParse("3415d786-bc03-4543-aa3c-5ec9e55aa460"),
+			Name: "user",
+			NullableGroup: &NullableGroup{
+				ID:   uuid.MustParse("3b35e207-c544-424e-b029-be31d5fe8bad"),
+				Name: "users",
+			},
+		},
+		{
+			ID:   uuid.MustParse("383e9a9b-ef95-421d-a89e-60f0344ee29d"),
+			Name: "without group",
+		},
+	}
+
+	tests := map[string]struct {
+		query         func(db *gorm.DB) *gorm.DB
+		expectedNames []string
+	}{
+		"not": {
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Not(map[string]any{
+					"nullable_group": map[string]any{"name": "admins"},
+				})
+			},
+			expectedNames: []string{"user", "without group"},
+		},
+		"not with multiple filters": {
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Not(map[string]any{
+					"name":           "user",
+					"nullable_group": map[string]any{"name": "admins"},
+				})
+			},
+			expectedNames: []string{"without group"},
+		},
+		"not in map": {
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Where(map[string]any{
+					"$not": map[string]any{
+						"nullable_group": map[string]any{"name": "users"},
+					},
+				})
+			},
+			expectedNames: []string{"admin", "without group"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			db := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = db.AutoMigrate(&NullableMember{}, &NullableGroup{})
+
+			if err := db.CreateInBatches(existing, 10).Error; err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+
+			// Act
+			err := db.Use(New())
+
+			// Assert
+			assert.Nil(t, err)
+
+			var actual []string
+			err = testData.query(db.Model(&NullableMember{})).Order("name").Pluck("name", &actual).Error
+			assert.Nil(t, err)
+
+			assert.Equal(t, testData.expectedNames, actual)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package deepgorm
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+// ErrFieldNotFilterable is returned if a filter uses a field that may not be filtered on, refer to Policy
+var ErrFieldNotFilterable = errors.New("field not filterable")
+
+// Policy godoc
+// Limits the fields that may be used in filters on a model, refer to WithPolicy. Paths are the keys from the model to
+// the field produced by the naming strategy, separated by dots, for example:
+//
+//	deepgorm.Policy{
+//		// Only the name of the user, its group and the names of its pets may be used
+//		Allow: []string{"name", "group", "pets.name"},
+//		// Except for the owner of the group and anything below it
+//		Deny: []string{"group.owner"},
+//	}
+//
+// Allowing a path allows everything below it, a relation or embedded struct may be used if anything below it is
+// allowed. Denying a path denies everything below it, denied paths take precedence over allowed ones.
+//
+// The policies of related models apply to the filters on them as well, using paths from the related model. A filter
+// like {"group": {"name": "admins"}} on users needs both the policy of users to allow "group.name" and the policy of
+// groups to allow "name".
+type Policy struct {
+	// Allow are the only paths that may be used, every path may be used if it's empty
+	Allow []string
+
+	// Deny are the paths that may not be used
+	Deny []string
+}
+
+// allows returns whether the path may be used in filters
+func (p Policy) allows(path string) bool {
+	for _, denied := range p.Deny {
+		if path == denied || strings.HasPrefix(path, denied+".") {
+			return false
+		}
+	}
+
+	if len(p.Allow) == 0 {
+		return true
+	}
+
+	for _, allowed := range p.Allow {
+		if path == allowed || strings.HasPrefix(path, allowed+".") || strings.HasPrefix(allowed, path+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isHidden returns whether the field or one of the structs it's embedded in has the `deepgorm:"-"` tag
+func isHidden(schemaInfo *schema.Schema, field *schema.Field) bool {
+	structType := schemaInfo.ModelType
+
+	for _, name := range field.BindNames {
+		structField, _ := ensureConcrete(structType).FieldByName(name)
+		structType = structField.Type
+
+		if hasHiddenTag(structField) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasHiddenTag returns whether the field has the `deepgorm:"-"` tag
+func hasHiddenTag(field reflect.StructField) bool {
+	return field.Tag.Get("deepgorm") == "-"
+}
+
+// policyOf returns the policy of the model, refer to WithPolicy
+func (d *DeepGorm) policyOf(schemaInfo *schema.Schema) Policy {
+	return d.policies[ensureConcrete(schemaInfo.ModelType)]
+}
+
+// isHiddenColumn returns whether the column, relation or embedded struct of the schema has the `deepgorm:"-"` tag
+func (d *DeepGorm) isHiddenColumn(naming schema.Namer, schemaInfo *schema.Schema, column string) bool {
+	if field, ok := schemaInfo.FieldsByDBName[column]; ok {
+		return isHidden(schemaInfo, field)
+	}
+
+	if relationship := getRelationship(naming, schemaInfo, column); relationship != nil {
+		return hasHiddenTag(relationship.Field.StructField)
+	}
+
+	if embedded, ok := d.getEmbeddedFieldsOfType(naming, schemaInfo)[column]; ok {
+		return hasHiddenTag(embedded.field)
+	}
+
+	return false
+}
@@ -0,0 +1,526 @@
+package deepgorm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ing-bank/gormtestutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type PolicyOwner struct {
+	ID    uuid.UUID
+	Name  string
+	Email string
+}
+
+type PolicyGroup struct {
+	ID      uuid.UUID
+	Name    string
+	OwnerID *uuid.UUID
+	Owner   *PolicyOwner
+}
+
+type PolicyAddress struct {
+	City   string
+	Street string `deepgorm:"-"`
+}
+
+type PolicyPet struct {
+	ID           uuid.UUID
+	PolicyUserID uuid.UUID
+	Name         string
+	Age          int
+}
+
+type PolicyUser struct {
+	ID           uuid.UUID
+	Name         string
+	PasswordHash string `deepgorm:"-"`
+	TenantID     int
+	GroupID      *uuid.UUID
+	Group        *PolicyGroup
+	Address      PolicyAddress `gorm:"embedded;embeddedPrefix:addr_"`
+	Pets         []*PolicyPet
+	Secrets      []*PolicyPet `gorm:"foreignKey:PolicyUserID" deepgorm:"-"`
+}
+
+// policyOptions allow the name, group, address and names of pets of users, except for the owners of groups
+var policyOptions = []Option{
+	WithPolicy(PolicyUser{}, Policy{Allow: []string{"name", "group", "address", "pets.name"}}),
+	WithPolicy(&PolicyUser{}, Policy{Deny: []string{"group.owner"}}),
+}
+
+func TestPolicy_Allows_ReturnsExpectedResult(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		policy   Policy
+		path     string
+		expected bool
+	}{
+		"empty policy": {
+			path:     "group.owner.name",
+			expected: true,
+		},
+		"allowed": {
+			policy:   Policy{Allow: []string{"name"}},
+			path:     "name",
+			expected: true,
+		},
+		"not allowed": {
+			policy:   Policy{Allow: []string{"name"}},
+			path:     "tenant_id",
+			expected: false,
+		},
+		"below allowed path": {
+			policy:   Policy{Allow: []string{"group"}},
+			path:     "group.owner.name",
+			expected: true,
+		},
+		"above allowed path": {
+			policy:   Policy{Allow: []string{"group.owner.name"}},
+			path:     "group",
+			expected: true,
+		},
+		"same prefix as allowed path": {
+			policy:   Policy{Allow: []string{"group"}},
+			path:     "group_id",
+			expected: false,
+		},
+		"denied": {
+			policy:   Policy{Deny: []string{"tenant_id"}},
+			path:     "tenant_id",
+			expected: false,
+		},
+		"below denied path": {
+			policy:   Policy{Deny: []string{"group.owner"}},
+			path:     "group.owner.name",
+			expected: false,
+		},
+		"denied and allowed": {
+			policy:   Policy{Allow: []string{"group"}, Deny: []string{"group.owner"}},
+			path:     "group.owner",
+			expected: false,
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Act
+			result := testData.policy.allows(testData.path)
+
+			// Assert
+			assert.Equal(t, testData.expected, result)
+		})
+	}
+}
+
+func TestDeepGorm_AddDeepFilters_AllowsFiltersInPolicy(t *testing.T) {
+	t.Parallel()
+	records := []*PolicyUser{
+		{
+			ID:      uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+			Name:    "Jake",
+			Group:   &PolicyGroup{ID: uuid.New(), Name: "admins"},
+			Address: PolicyAddress{City: "Utrecht"},
+			Pets:    []*PolicyPet{{ID: uuid.New(), Name: "Bello", Age: 3}},
+		},
+		{
+			ID:      uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+			Name:    "Anna",
+			Address: PolicyAddress{City: "Amsterdam"},
+		},
+	}
+
+	tests := map[string]struct {
+		filterMap map[string]any
+		expected  []string
+	}{
+		"column": {
+			filterMap: map[string]any{"name": "Anna"},
+			expected:  []string{"Anna"},
+		},
+		"relation": {
+			filterMap: map[string]any{"group": map[string]any{"name": "admins"}},
+			expected:  []string{"Jake"},
+		},
+		"relation without its fields": {
+			filterMap: map[string]any{"pets": nil},
+			expected:  []string{"Anna"},
+		},
+		"quantifier": {
+			filterMap: map[string]any{"pets": map[string]any{"$all": map[string]any{"name": "Bello"}, "$count": 1}},
+			expected:  []string{"Jake"},
+		},
+		"embedded struct": {
+			filterMap: map[string]any{"address": map[string]any{"city": "Utrecht"}},
+			expected:  []string{"Jake"},
+		},
+		"logical combinator": {
+			filterMap: map[string]any{"$or": []map[string]any{{"name": "Anna"}, {"pets": map[string]any{"name": "Bello"}}}},
+			expected:  []string{"Anna", "Jake"},
+		},
+		"relation in policy of related model": {
+			filterMap: map[string]any{"group": map[string]any{"$or": []map[string]any{{"name": "admins"}, {"name": "users"}}}},
+			expected:  []string{"Jake"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&PolicyUser{}, &PolicyGroup{}, &PolicyOwner{}, &PolicyPet{})
+
+			database.CreateInBatches(records, len(records))
+
+			plugin := New(append([]Option{WithPolicy(PolicyGroup{}, Policy{Allow: []string{"name"}})}, policyOptions...)...)
+
+			// Act
+			query, err := plugin.AddDeepFilters(database, PolicyUser{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []string
+				res := query.Model(&PolicyUser{}).Order("name").Pluck("name", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, testData.expected, result)
+			}
+		})
+	}
+}
+
+func TestDeepGorm_AddDeepFilters_ReturnsErrorOnFieldsOutsidePolicy(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		options      []Option
+		filterMap    map[string]any
+		expectedPath string
+		expectedMsg  string
+	}{
+		"hidden column": {
+			filterMap:    map[string]any{"password_hash": "abc"},
+			expectedPath: "password_hash",
+			expectedMsg:  "failed to add filters for 'policy_users.password_hash': 'password_hash' can't be used in filters: field not filterable",
+		},
+		"hidden relation": {
+			filterMap:    map[string]any{"secrets": map[string]any{"name": "abc"}},
+			expectedPath: "secrets",
+			expectedMsg:  "failed to add filters for 'policy_users.secrets': 'secrets' can't be used in filters: field not filterable",
+		},
+		"hidden column of embedded struct": {
+			filterMap:    map[string]any{"address": map[string]any{"street": "abc"}},
+			expectedPath: "address.street",
+			expectedMsg:  "failed to add filters for 'policy_users.addr_street': 'address.street' can't be used in filters: field not filterable",
+		},
+		"hidden column of embedded struct by its column name": {
+			filterMap:    map[string]any{"addr_street": "abc"},
+			expectedPath: "addr_street",
+			expectedMsg:  "failed to add filters for 'policy_users.addr_street': 'addr_street' can't be used in filters: field not filterable",
+		},
+		"column not in policy": {
+			options:      policyOptions,
+			filterMap:    map[string]any{"tenant_id": 5},
+			expectedPath: "tenant_id",
+			expectedMsg:  "failed to add filters for 'policy_users.tenant_id': 'tenant_id' can't be used in filters: field not filterable",
+		},
+		"denied relation": {
+			options:      policyOptions,
+			filterMap:    map[string]any{"group": map[string]any{"owner": map[string]any{"email": "abc"}}},
+			expectedPath: "group.owner",
+			expectedMsg:  "failed to add filters for 'policy_groups.owner': 'group.owner' can't be used in filters: field not filterable",
+		},
+		"column of relation not in policy": {
+			options:      policyOptions,
+			filterMap:    map[string]any{"$or": []map[string]any{{"name": "abc"}, {"pets": map[string]any{"age": 5}}}},
+			expectedPath: "$or.1.pets.age",
+			expectedMsg:  "failed to add filters for 'policy_pets.age': 'pets.age' can't be used in filters: field not filterable",
+		},
+		"column of quantifier not in policy": {
+			options:      policyOptions,
+			filterMap:    map[string]any{"pets": map[string]any{"$none": map[string]any{"age": 5}}},
+			expectedPath: "pets.$none.age",
+			expectedMsg:  "failed to add filters for 'policy_pets.age': 'pets.age' can't be used in filters: field not filterable",
+		},
+		"column of aggregate not in policy": {
+			options:      policyOptions,
+			filterMap:    map[string]any{"pets": map[string]any{"$max": map[string]any{"age": 5}}},
+			expectedPath: "pets.$max.age",
+			expectedMsg:  "failed to add filters for 'policy_pets.age': 'pets.age' can't be used in filters: field not filterable",
+		},
+		"policy of related model": {
+			options:      []Option{WithPolicy(PolicyGroup{}, Policy{Deny: []string{"name"}})},
+			filterMap:    map[string]any{"group": map[string]any{"name": "admins"}},
+			expectedPath: "group.name",
+			expectedMsg:  "failed to add filters for 'policy_groups.name': 'group.name' can't be used in filters: field not filterable",
+		},
+		"relation denied by policy of related model": {
+			options:      []Option{WithPolicy(PolicyGroup{}, Policy{Allow: []string{"name"}})},
+			filterMap:    map[string]any{"group": map[string]any{"name": "admins", "owner": map[string]any{"name": "abc"}}},
+			expectedPath: "group.owner",
+			expectedMsg:  "failed to add filters for 'policy_groups.owner': 'group.owner' can't be used in filters: field not filterable",
+		},
+		"policy of model below another relation": {
+			options:      []Option{WithPolicy(PolicyOwner{}, Policy{Deny: []string{"email"}})},
+			filterMap:    map[string]any{"group": map[string]any{"owner": map[string]any{"email": "abc"}}},
+			expectedPath: "group.owner.email",
+			expectedMsg:  "failed to add filters for 'policy_owners.email': 'group.owner.email' can't be used in filters: field not filterable",
+		},
+		"policy of related model in quantifier": {
+			options:      []Option{WithPolicy(PolicyPet{}, Policy{Deny: []string{"age"}})},
+			filterMap:    map[string]any{"pets": map[string]any{"$any": map[string]any{"age": 5}}},
+			expectedPath: "pets.$any.age",
+			expectedMsg:  "failed to add filters for 'policy_pets.age': 'pets.age' can't be used in filters: field not filterable",
+		},
+		"policy of related model in aggregate": {
+			options:      []Option{WithPolicy(PolicyPet{}, Policy{Allow: []string{"name"}})},
+			filterMap:    map[string]any{"pets": map[string]any{"$sum": map[string]any{"age": 5}}},
+			expectedPath: "pets.$sum.age",
+			expectedMsg:  "failed to add filters for 'policy_pets.age': 'pets.age' can't be used in filters: field not filterable",
+		},
+		"alias": {
+			options:      append([]Option{WithKeys(KeyByFieldName)}, policyOptions...),
+			filterMap:    map[string]any{"Name": "abc", "Group": map[string]any{"Owner": nil}},
+			expectedPath: "Group.Owner",
+			expectedMsg:  "failed to add filters for 'policy_groups.owner': 'group.owner' can't be used in filters: field not filterable",
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			plugin := New(testData.options...)
+
+			// Act
+			query, err := plugin.AddDeepFilters(database, PolicyUser{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, query)
+			assert.ErrorIs(t, err, ErrFieldNotFilterable)
+			assert.EqualError(t, err, testData.expectedMsg)
+
+			var filterError *FilterError
+			require.True(t, errors.As(err, &filterError))
+			assert.Equal(t, KindNotFilterable, filterError.Kind)
+			assert.Equal(t, testData.expectedPath, filterError.Path)
+		})
+	}
+}
+
+func TestDeepGorm_AddDeepFilters_ReturnsAllFieldsOutsidePolicy(t *testing.T) {
+	t.Parallel()
+	// Arrange
+	database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+	plugin := New(append([]Option{WithAllErrors()}, policyOptions...)...)
+
+	// Act
+	query, err := plugin.AddDeepFilters(database, PolicyUser{}, map[string]any{"tenant_id": 5, "password_hash": "abc", "unknown": 1})
+
+	// Assert
+	assert.Nil(t, query)
+	assert.EqualError(t, err, "failed to add filters for 'policy_users.password_hash': 'password_hash' can't be used in filters: field not filterable\n"+
+		"failed to add filters for 'policy_users.tenant_id': 'tenant_id' can't be used in filters: field not filterable\n"+
+		"failed to add filters for 'policy_users.unknown': field does not exist")
+}
+
+func TestDeepGorm_AddDeepFilters_ReturnsFieldsOutsidePolicyWithOtherProblems(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedPaths []string
+		expectedKinds []FilterErrorKind
+	}{
+		"columns": {
+			filterMap:     map[string]any{"name": "Jake", "zzz": 1, "id": "not-a-uuid"},
+			expectedPaths: []string{"id", "name", "zzz"},
+			expectedKinds: []FilterErrorKind{KindTypeMismatch, KindNotFilterable, KindUnknownField},
+		},
+		"problems below a field outside the policy": {
+			filterMap:     map[string]any{"group": map[string]any{"owner": map[string]any{"nam": "Anna"}, "id": "abc"}},
+			expectedPaths: []string{"group.id", "group.owner"},
+			expectedKinds: []FilterErrorKind{KindTypeMismatch, KindNotFilterable},
+		},
+		"logical combinators": {
+			filterMap: map[string]any{"$or": []map[string]any{
+				{"tenant_id": 1}, {"tenant_id": 2}, {"name": "Jake"}, {"tenant_id": 4}, {"tenant_id": 5}, {"tenant_id": 6},
+				{"tenant_id": 7}, {"tenant_id": 8}, {"tenant_id": 9}, {"tenant_id": 10}, {"nam": "Jake"},
+			}},
+			expectedPaths: []string{"$or.2.name", "$or.10.nam"},
+			expectedKinds: []FilterErrorKind{KindNotFilterable, KindUnknownField},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			plugin := New(WithAllErrors(), WithPolicy(PolicyUser{}, Policy{Deny: []string{"name", "group.owner"}}))
+
+			// Act
+			query, err := plugin.AddDeepFilters(database, PolicyUser{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, query)
+
+			var paths []string
+			var kinds []FilterErrorKind
+
+			for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+				var filterError *FilterError
+				require.True(t, errors.As(err, &filterError))
+
+				paths = append(paths, filterError.Path)
+				kinds = append(kinds, filterError.Kind)
+			}
+
+			assert.Equal(t, testData.expectedPaths, paths)
+			assert.Equal(t, testData.expectedKinds, kinds)
+		})
+	}
+}
+
+func TestDeepGorm_AddDeepFilters_ReturnsFirstProblemInKeyOrder(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedError error
+		expectedMsg   string
+	}{
+		"unknown field first": {
+			filterMap:     map[string]any{"name": "Jake", "colour": "blue"},
+			expectedError: ErrFieldDoesNotExist,
+			expectedMsg:   "failed to add filters for 'policy_users.colour': field does not exist",
+		},
+		"field outside policy first": {
+			filterMap:     map[string]any{"name": "Jake", "zzz": 1},
+			expectedError: ErrFieldNotFilterable,
+			expectedMsg:   "failed to add filters for 'policy_users.name': 'name' can't be used in filters: field not filterable",
+		},
+		"nested": {
+			filterMap:     map[string]any{"group": map[string]any{"owner": map[string]any{"name": "Anna"}, "id": "abc"}},
+			expectedError: ErrTypeMismatch,
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			plugin := New(WithPolicy(PolicyUser{}, Policy{Deny: []string{"name", "group.owner"}}))
+
+			// Act
+			query, err := plugin.AddDeepFilters(database, PolicyUser{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, query)
+			assert.ErrorIs(t, err, testData.expectedError)
+
+			if testData.expectedMsg != "" {
+				assert.EqualError(t, err, testData.expectedMsg)
+			}
+		})
+	}
+}
+
+func TestDeepGorm_Initialize_EnforcesPolicy(t *testing.T) {
+	t.Parallel()
+	records := []*PolicyUser{
+		{ID: uuid.New(), Name: "Jake", TenantID: 1, Group: &PolicyGroup{ID: uuid.New(), Name: "admins"}},
+		{ID: uuid.New(), Name: "Anna", TenantID: 2},
+	}
+
+	tests := map[string]struct {
+		filter      any
+		expected    []string
+		expectedErr error
+	}{
+		"allowed column": {
+			filter:   map[string]any{"name": "Anna"},
+			expected: []string{"Anna"},
+		},
+		"allowed relation": {
+			filter:   map[string]any{"group": map[string]any{"name": "admins"}},
+			expected: []string{"Jake"},
+		},
+		"hidden column": {
+			filter:      map[string]any{"password_hash": "abc"},
+			expectedErr: ErrFieldNotFilterable,
+		},
+		"column not in policy": {
+			filter:      map[string]any{"tenant_id": []int{1, 2}},
+			expectedErr: ErrFieldNotFilterable,
+		},
+		"denied relation": {
+			filter:      map[string]any{"group": map[string]any{"owner": nil}},
+			expectedErr: ErrFieldNotFilterable,
+		},
+		"allowed column with table": {
+			filter:   map[string]any{"policy_users.name": "Anna"},
+			expected: []string{"Anna"},
+		},
+		"hidden column with table": {
+			filter:      map[string]any{"policy_users.password_hash": "abc"},
+			expectedErr: ErrFieldNotFilterable,
+		},
+		"column not in policy with table": {
+			filter:      map[string]any{"policy_users.tenant_id": []int{1, 2}},
+			expectedErr: ErrFieldNotFilterable,
+		},
+		"allowed column of struct": {
+			filter:   &PolicyUser{Name: "Anna"},
+			expected: []string{"Anna"},
+		},
+		"hidden column of struct": {
+			filter:      &PolicyUser{PasswordHash: "abc"},
+			expectedErr: ErrFieldNotFilterable,
+		},
+		"column not in policy of struct": {
+			filter:      PolicyUser{TenantID: 1},
+			expectedErr: ErrFieldNotFilterable,
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&PolicyUser{}, &PolicyGroup{}, &PolicyOwner{}, &PolicyPet{})
+			_ = database.Use(New(policyOptions...))
+
+			database.CreateInBatches(records, len(records))
+
+			// Act
+			var result []string
+			res := database.Model(&PolicyUser{}).Where(testData.filter).Order("name").Pluck("name", &result)
+
+			// Assert
+			if testData.expectedErr != nil {
+				assert.ErrorIs(t, res.Error, testData.expectedErr)
+				return
+			}
+
+			assert.Nil(t, res.Error)
+			assert.Equal(t, testData.expected, result)
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package deepgorm
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// relationOperatorNames are the operators that may be used in the filter of a relation, refer to buildRelationExpression
+var relationOperatorNames = map[string]bool{
+	"$notNull": true,
+	"$any":     true,
+	"$all":     true,
+	"$none":    true,
+	"$exists":  true,
+	"$count":   true,
+	"$sum":     true,
+	"$avg":     true,
+	"$min":     true,
+	"$max":     true,
+}
+
+// buildRelationExpression godoc
+// Creates the subquery condition for a relation. By default, a record matches if at least one of its related
+// records matches the filter. Whether there is a related record at all can be checked on any relation using
+// $notNull, refer to buildRelationNullExpression. To-many relations also support quantifiers:
+//
+//	map[string]any{
+//		"object_bs": map[string]any{
+//			// At least one related ObjectB is named 'abc', same as leaving out $any
+//			"$any": map[string]any{"name": "abc"},
+//			// Every related ObjectB is named 'abc', also true if there are none
+//			"$all": map[string]any{"name": "abc"},
+//			// No related ObjectB is named 'abc', also true if there are none
+//			"$none": map[string]any{"name": "abc"},
+//			// There is at least one related ObjectB, false is the same as "$none": map[string]any{}
+//			"$exists": true,
+//			// There are at least 3 related ObjectBs, refer to buildCountExpression
+//			"$count": map[string]any{"$gte": 3},
+//			// The related ObjectBs' amounts add up to more than 1000, refer to buildAggregateExpression
+//			"$sum": map[string]any{"amount": map[string]any{"$gt": 1000}},
+//		},
+//	}
+//
+// These may be combined with each other and with regular filters, all of them are combined using AND.
+func (d *DeepGorm) buildRelationExpression(db *gorm.DB, schemaInfo *schema.Schema, fieldName string, fieldInfo *nestedType, filter map[string]any) (clause.Expression, error) {
+	relationOperators := map[string]any{}
+	unquantified := map[string]any{}
+
+	for key, value := range filter {
+		if relationOperatorNames[key] {
+			relationOperators[key] = value
+		} else {
+			unquantified[key] = value
+		}
+	}
+
+	// Sort the operators to get a predictable query
+	keys := sortedKeys(relationOperators)
+
+	for _, key := range keys {
+		if key != "$notNull" && fieldInfo.relationType != "manyToOne" && fieldInfo.relationType != "manyToMany" {
+			return nil, newRelationOperatorError(schemaInfo, fieldName, key, filter[key], fmt.Errorf("'%s' can only be used on to-many relations: %w", key, ErrUnsupportedOperator))
+		}
+	}
+
+	expressions := make([]clause.Expression, 0, len(relationOperators)+1)
+
+	var errs []error
+
+	// Regular filters on a relation mean 'any', leaving them out only if a relation operator is used
+	if len(unquantified) > 0 || len(relationOperators) == 0 {
+		expression, err := d.addDeepFilter(db, schemaInfo, fieldInfo, unquantified, false)
+		if err != nil {
+			errs = append(errs, prependPath(err, fieldName))
+		} else {
+			expressions = append(expressions, expression)
+		}
+	}
+
+	for _, key := range keys {
+		expression, err := d.buildRelationOperatorExpression(db, schemaInfo, fieldName, fieldInfo, key, relationOperators[key])
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		expressions = append(expressions, expression)
+	}
+
+	if len(errs) > 0 {
+		return nil, joinErrors(errs)
+	}
+
+	return clause.And(expressions...), nil
+}
+
+// buildRelationOperatorExpression creates the condition for a single relation operator, refer to buildRelationExpression
+func (d *DeepGorm) buildRelationOperatorExpression(db *gorm.DB, schemaInfo *schema.Schema, fieldName string, fieldInfo *nestedType, name string, value any) (clause.Expression, error) {
+	switch name {
+	case "$exists", "$notNull":
+		exists, ok := value.(bool)
+		if !ok {
+			return nil, newRelationOperatorError(schemaInfo, fieldName, name, value, fmt.Errorf("'%s' expects a boolean: %w", name, ErrInvalidOperatorValue))
+		}
+
+		return d.buildRelationNullExpression(db, schemaInfo, fieldInfo, exists)
+
+	case "$count":
+		return buildCountExpression(schemaInfo, fieldName, fieldInfo, value)
+
+	case "$sum", "$avg", "$min", "$max":
+		return d.buildAggregateExpression(db, schemaInfo, fieldName, fieldInfo, name, value)
+	}
+
+	quantifiedFilter, ok := value.(map[string]any)
+	if !ok {
+		return nil, newRelationOperatorError(schemaInfo, fieldName, name, value, fmt.Errorf("'%s' expects a filter map: %w", name, ErrInvalidOperatorValue))
+	}
+
+	// 'all' is the same as 'none of the records that don't match'
+	expression, err := d.addDeepFilter(db, schemaInfo, fieldInfo, quantifiedFilter, name == "$all")
+	if err != nil {
+		return nil, prependPath(err, fieldName, name)
+	}
+
+	if name == "$any" {
+		return expression, nil
+	}
+
+	return negateExpression(expression), nil
+}
+
+// buildRelationNullExpression godoc
+// Matches the records without any related records, or the ones with related records if notNull is true:
+//
+//	map[string]any{
+//		// Users without a group
+//		"group": nil,
+//		// Users with at least one pet
+//		"pets": map[string]any{"$notNull": true},
+//	}
+//
+// A belongs-to relation is NULL if its foreign key is NULL, the related record itself isn't looked up.
+func (d *DeepGorm) buildRelationNullExpression(db *gorm.DB, schemaInfo *schema.Schema, fieldInfo *nestedType, notNull bool) (clause.Expression, error) {
+	if fieldInfo.relationType == "oneToMany" {
+		foreignKeys := columnsOf(schemaInfo.Table, fieldInfo.fieldForeignKeys)
+		isNull := relationExpression{positive: anyNull(foreignKeys), negative: noneNull(foreignKeys)}
+
+		if notNull {
+			return negateExpression(isNull), nil
+		}
+
+		return isNull, nil
+	}
+
+	expression, err := d.addDeepFilter(db, schemaInfo, fieldInfo, map[string]any{}, false)
+	if err != nil || notNull {
+		return expression, err
+	}
+
+	return negateExpression(expression), nil
+}
+
+// newRelationOperatorError creates a FilterError for an operator on the relation, the path refers to the operator
+func newRelationOperatorError(schemaInfo *schema.Schema, fieldName string, name string, value any, err error) *FilterError {
+	filterError := newFilterError(schemaInfo, fieldName, value, err)
+	filterError.Path = fieldName + "." + name
+
+	return filterError
+}
@@ -0,0 +1,286 @@
+package deepgorm
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ing-bank/gormtestutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddDeepFilters_AddsQuantifiedFiltersWithManyToOne(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	records := []*ComplexStruct2{
+		{
+			ID:   uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+			Name: "all dev",
+			Tags: []*SimpleTag{
+				{ID: uuid.MustParse("1c83a7c9-e95d-4dba-b858-5eb4e34ebcf2"), Key: "team", Value: "dev"},
+				{ID: uuid.MustParse("17983ba8-2d26-4e36-bb6b-6c5a04b6606e"), Key: "team", Value: "dev"},
+			},
+		},
+		{
+			ID:   uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+			Name: "mixed",
+			Tags: []*SimpleTag{
+				{ID: uuid.MustParse("451d635a-83f2-47da-b12c-50ec49e45509"), Key: "team", Value: "dev"},
+				{ID: uuid.MustParse("8977cd8b-ebb8-4119-93d5-cbe605d8f668"), Key: "team", Value: "ops"},
+			},
+		},
+		{
+			ID:   uuid.MustParse("411ed385-c1ca-432d-b577-6d6138450264"),
+			Name: "all ops",
+			Tags: []*SimpleTag{
+				{ID: uuid.MustParse("8927cd8b-ebb8-4119-93d5-cbe605d8f668"), Key: "team", Value: "ops"},
+			},
+		},
+		{
+			ID:   uuid.MustParse("c98dc9f2-bfa5-4ab5-9cbb-76800e09e512"),
+			Name: "empty",
+		},
+	}
+
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedNames []string
+	}{
+		"any": {
+			filterMap: map[string]any{
+				"tags": map[string]any{
+					"$any": map[string]any{"value": "dev"},
+				},
+			},
+			expectedNames: []string{"all dev", "mixed"},
+		},
+		"all": {
+			filterMap: map[string]any{
+				"tags": map[string]any{
+					"$all": map[string]any{"value": "dev"},
+				},
+			},
+			expectedNames: []string{"all dev", "empty"},
+		},
+		"none": {
+			filterMap: map[string]any{
+				"tags": map[string]any{
+					"$none": map[string]any{"value": "dev"},
+				},
+			},
+			expectedNames: []string{"all ops", "empty"},
+		},
+		"none at all": {
+			filterMap: map[string]any{
+				"tags": map[string]any{
+					"$none": map[string]any{},
+				},
+			},
+			expectedNames: []string{"empty"},
+		},
+		"all and any to exclude empty collections": {
+			filterMap: map[string]any{
+				"tags": map[string]any{
+					"$all": map[string]any{"value": "dev"},
+					"$any": map[string]any{},
+				},
+			},
+			expectedNames: []string{"all dev"},
+		},
+		"all combined with regular filter": {
+			filterMap: map[string]any{
+				"tags": map[string]any{
+					"$all":  map[string]any{"value": map[string]any{"$ne": "dev"}},
+					"value": "ops",
+				},
+			},
+			expectedNames: []string{"all ops"},
+		},
+		"not all": {
+			filterMap: map[string]any{
+				"$not": map[string]any{
+					"tags": map[string]any{
+						"$all": map[string]any{"value": "dev"},
+					},
+				},
+			},
+			expectedNames: []string{"all ops", "mixed"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&ComplexStruct2{}, &SimpleTag{})
+
+			database.CreateInBatches(records, len(records))
+
+			// Act
+			query, err := AddDeepFilters(database, ComplexStruct2{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []string
+				res := query.Model(&ComplexStruct2{}).Order("name").Pluck("name", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, testData.expectedNames, result)
+			}
+		})
+	}
+}
+
+func TestAddDeepFilters_AddsQuantifiedFiltersWithManyToMany(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	world := &ManyB{ID: uuid.MustParse("9f1baf72-6ca5-4d43-8a01-d845575620e1"), B: "world"}
+	moon := &ManyB{ID: uuid.MustParse("4604bb79-ee05-4a09-b874-c3af8964d8c4"), B: "moon"}
+
+	records := []*ManyA{
+		{
+			ID:     uuid.MustParse("59aa5a8f-c5de-44fa-9355-080650481687"),
+			A:      "world only",
+			ManyBs: []*ManyB{world},
+		},
+		{
+			ID:     uuid.MustParse("23292d51-4768-4c41-8475-6d4c9f0c6f69"),
+			A:      "world and moon",
+			ManyBs: []*ManyB{world, moon},
+		},
+		{
+			ID: uuid.MustParse("c98dc9f2-bfa5-4ab5-9cbb-76800e09e512"),
+			A:  "empty",
+		},
+	}
+
+	tests := map[string]struct {
+		filterMap     map[string]any
+		expectedNames []string
+	}{
+		"any": {
+			filterMap: map[string]any{
+				"many_bs": map[string]any{
+					"$any": map[string]any{"b": "moon"},
+				},
+			},
+			expectedNames: []string{"world and moon"},
+		},
+		"all": {
+			filterMap: map[string]any{
+				"many_bs": map[string]any{
+					"$all": map[string]any{"b": "world"},
+				},
+			},
+			expectedNames: []string{"empty", "world only"},
+		},
+		"none": {
+			filterMap: map[string]any{
+				"many_bs": map[string]any{
+					"$none": map[string]any{"b": "moon"},
+				},
+			},
+			expectedNames: []string{"empty", "world only"},
+		},
+		"none at all": {
+			filterMap: map[string]any{
+				"many_bs": map[string]any{
+					"$none": map[string]any{},
+				},
+			},
+			expectedNames: []string{"empty"},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			_ = database.AutoMigrate(&ManyA{}, &ManyB{})
+
+			database.CreateInBatches(records, len(records))
+
+			// Act
+			query, err := AddDeepFilters(database, ManyA{}, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, err)
+
+			if assert.NotNil(t, query) {
+				var result []string
+				res := query.Model(&ManyA{}).Order("a").Pluck("a", &result)
+
+				// Handle error
+				assert.Nil(t, res.Error)
+
+				assert.Equal(t, testData.expectedNames, result)
+			}
+		})
+	}
+}
+
+func TestAddDeepFilters_ReturnsErrorOnInvalidQuantifiers(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	tests := map[string]struct {
+		objectType    any
+		filterMap     map[string]any
+		expectedError error
+		expectedMsg   string
+	}{
+		"quantifier on single relation": {
+			objectType: ComplexStruct1{},
+			filterMap: map[string]any{
+				"nested": map[string]any{
+					"$all": map[string]any{"name": "abc"},
+				},
+			},
+			expectedError: ErrUnsupportedOperator,
+			expectedMsg:   "failed to add filters for 'complex_struct1.nested': '$all' can only be used on to-many relations: unsupported operator",
+		},
+		"quantifier with a list": {
+			objectType: ComplexStruct2{},
+			filterMap: map[string]any{
+				"tags": map[string]any{
+					"$none": []map[string]any{{"value": "abc"}},
+				},
+			},
+			expectedError: ErrInvalidOperatorValue,
+			expectedMsg:   "failed to add filters for 'complex_struct2.tags': '$none' expects a filter map: invalid operator value",
+		},
+		"unknown field in quantifier": {
+			objectType: ComplexStruct2{},
+			filterMap: map[string]any{
+				"tags": map[string]any{
+					"$any": map[string]any{"unknown": "abc"},
+				},
+			},
+			expectedError: ErrFieldDoesNotExist,
+			expectedMsg:   "failed to add filters for 'simple_tags.unknown': field does not exist",
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+
+			// Act
+			query, err := AddDeepFilters(database, testData.objectType, testData.filterMap)
+
+			// Assert
+			assert.Nil(t, query)
+			assert.ErrorIs(t, err, testData.expectedError)
+			assert.EqualError(t, err, testData.expectedMsg)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package deepgorm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ErrInvalidRelation is returned by Register if a field can't be used to filter on a relation
+var ErrInvalidRelation = errors.New("invalid relation")
+
+// Register parses the models using an instance with the default configuration and caches, refer to DeepGorm.Register.
+func Register(db *gorm.DB, models ...any) error {
+	return defaultDeepGorm.Register(db, models...)
+}
+
+// Register godoc
+// Parses the models and the models they're related to up front and warms the caches, so a typo in a tag like
+// foreignKey is found at startup instead of as ErrFieldDoesNotExist once someone filters on it. Returns every
+// problem that was found, one per line, for example:
+//
+//	failed to register 'users.pets': no foreign key found for field Pets: invalid relation
+//	failed to register 'companies.users': invalid field found for struct deepgorm.Company's field Users: ...: invalid relation
+//
+// Gorm stops parsing a model at its first invalid relation, so only the first one of every model is reported.
+func (d *DeepGorm) Register(db *gorm.DB, models ...any) error {
+	var errs []error
+
+	visited := map[reflect.Type]bool{}
+
+	for _, model := range models {
+		errs = append(errs, d.registerModel(db, model, visited)...)
+	}
+
+	return errors.Join(errs...)
+}
+
+// registerModel parses a single model and follows its relations, visited prevents parsing a model twice
+func (d *DeepGorm) registerModel(db *gorm.DB, model any, visited map[reflect.Type]bool) []error {
+	modelType := ensureConcrete(ensureNotASlice(reflect.TypeOf(model)))
+	if visited[modelType] {
+		return nil
+	}
+
+	visited[modelType] = true
+
+	schemaInfo, err := d.parseSchema(db, model)
+	if err != nil {
+		if field := invalidRelationField(schemaInfo); field != nil {
+			fieldName := db.NamingStrategy.ColumnName(schemaInfo.Table, field.Name)
+			return []error{fmt.Errorf("failed to register '%s.%s': %w: %w", schemaInfo.Table, fieldName, err, ErrInvalidRelation)}
+		}
+
+		return []error{fmt.Errorf("failed to register '%s': %w", modelType, err)}
+	}
+
+	// Warm the caches
+	_ = d.getDatabaseFieldsOfType(db.NamingStrategy, schemaInfo)
+	_ = d.getKeysOfType(db.NamingStrategy, schemaInfo)
+
+	// Sorted to get a predictable report
+	names := sortedKeys(schemaInfo.Relationships.Relations)
+
+	var errs []error
+
+	for _, name := range names {
+		relationship := schemaInfo.Relationships.Relations[name]
+
+		fieldInfo, err := getNestedType(relationship)
+		if err != nil {
+			fieldName := db.NamingStrategy.ColumnName(schemaInfo.Table, name)
+			errs = append(errs, fmt.Errorf("failed to register '%s.%s': %w: %w", schemaInfo.Table, fieldName, err, ErrInvalidRelation))
+			continue
+		}
+
+		errs = append(errs, d.registerModel(db, fieldInfo.fieldStructInstance, visited)...)
+	}
+
+	return errs
+}
+
+// invalidRelationField returns the relation gorm failed to parse. Gorm parses the relations in the order of the fields
+// and stops at the first invalid one, so it's the first relation that's missing from the partially parsed schema.
+func invalidRelationField(schemaInfo *schema.Schema) *schema.Field {
+	if schemaInfo == nil {
+		return nil
+	}
+
+	for _, field := range schemaInfo.Fields {
+		isRelation := field.DataType == "" && field.GORMDataType == "" && (field.Creatable || field.Updatable || field.Readable)
+		if !isRelation {
+			continue
+		}
+
+		if _, ok := schemaInfo.Relationships.Relations[field.Name]; !ok {
+			return field
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package deepgorm
+
+import (
+	"testing"
+
+	"github.com/ing-bank/gormtestutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type RegisterPet struct {
+	ID      int
+	OwnerID int
+}
+
+type RegisterTypoOwner struct {
+	ID   int
+	Pets []*RegisterPet `gorm:"foreignKey:OwnrID"`
+}
+
+type RegisterKeylessTag struct {
+	Name           string
+	RegisterItemID int
+}
+
+type RegisterItem struct {
+	ID   int
+	Tags []*RegisterKeylessTag
+}
+
+type RegisterOrder struct {
+	ID     int
+	ItemID int
+	Item   *RegisterItem
+}
+
+func TestDeepGorm_Register_ReturnsNilOnValidModels(t *testing.T) {
+	t.Parallel()
+	// Arrange
+	database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+	plugin := New()
+
+	// Act
+	err := plugin.Register(database, ObjectA{}, &ObjectB{}, []*ObjectA{})
+
+	// Assert
+	assert.Nil(t, err)
+
+	count := 0
+	plugin.relationCache.Range(func(cacheKey, map[string]*nestedType) bool { count++; return true })
+	assert.Equal(t, 2, count)
+}
+
+func TestDeepGorm_Register_ReturnsErrorOnInvalidRelations(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		models      []any
+		expectedMsg string
+	}{
+		"invalid foreign key": {
+			models:      []any{RegisterTypoOwner{}},
+			expectedMsg: "failed to register 'register_typo_owners.pets': invalid field found for struct github.com/survivorbat/gorm-deep-filtering.RegisterTypoOwner's field Pets: define a valid foreign key for relations or implement the Valuer/Scanner interface: invalid relation",
+		},
+		"no primary key": {
+			models:      []any{RegisterItem{}},
+			expectedMsg: "failed to register 'register_items.tags': no primary key found for the type of field Tags: invalid relation",
+		},
+		"through a relation": {
+			models:      []any{RegisterOrder{}},
+			expectedMsg: "failed to register 'register_items.tags': no primary key found for the type of field Tags: invalid relation",
+		},
+		"all models": {
+			models: []any{RegisterTypoOwner{}, ObjectA{}, RegisterOrder{}, RegisterItem{}},
+			expectedMsg: "failed to register 'register_typo_owners.pets': invalid field found for struct github.com/survivorbat/gorm-deep-filtering.RegisterTypoOwner's field Pets: define a valid foreign key for relations or implement the Valuer/Scanner interface: invalid relation\n" +
+				"failed to register 'register_items.tags': no primary key found for the type of field Tags: invalid relation",
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			plugin := New()
+
+			// Act
+			err := plugin.Register(database, testData.models...)
+
+			// Assert
+			assert.EqualError(t, err, testData.expectedMsg)
+		})
+	}
+}
+
+func TestDeepGorm_Register_ReturnsRelationErrors(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		model any
+	}{
+		"no primary key": {
+			model: RegisterItem{},
+		},
+		"invalid foreign key": {
+			model: RegisterTypoOwner{},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+			plugin := New()
+
+			// Act
+			err := plugin.Register(database, testData.model)
+
+			// Assert
+			assert.ErrorIs(t, err, ErrInvalidRelation)
+		})
+	}
+}
+
+func TestDeepGorm_Initialize_RegistersModels(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		models      []any
+		expectedMsg string
+	}{
+		"valid": {
+			models: []any{ObjectA{}},
+		},
+		"invalid": {
+			models:      []any{ObjectA{}, RegisterItem{}},
+			expectedMsg: "failed to register 'register_items.tags': no primary key found for the type of field Tags: invalid relation",
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			database := gormtestutil.NewMemoryDatabase(t, gormtestutil.WithName(t.Name()))
+
+			// Act
+			err := database.Use(New(WithModels(testData.models...)))
+
+			// Assert
+			if testData.expectedMsg == "" {
+				assert.Nil(t, err)
+				return
+			}
+
+			assert.EqualError(t, err, testData.expectedMsg)
+		})
+	}
+}
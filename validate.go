@@ -0,0 +1,105 @@
+package deepgorm
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// Compile-time interface check
+var _ gorm.Dialector = validationDialector{}
+
+// ValidateOption configures Validate
+type ValidateOption func(*validateConfig)
+
+// validateConfig is the database that Validate pretends to use
+type validateConfig struct {
+	naming  schema.Namer
+	dialect string
+}
+
+// WithNamingStrategy sets the naming strategy that the database uses, the keys in the filter are column names
+// produced by it. Defaults to gorm's schema.NamingStrategy{}.
+func WithNamingStrategy(naming schema.Namer) ValidateOption {
+	return func(c *validateConfig) {
+		c.naming = naming
+	}
+}
+
+// WithDialect sets the name of the database's dialector, like 'postgres'. Filters on JSON columns are only valid on
+// databases that support them. Defaults to sqlite.
+func WithDialect(name string) ValidateOption {
+	return func(c *validateConfig) {
+		c.dialect = name
+	}
+}
+
+// Validate checks the filter using an instance with the default configuration and caches, refer to DeepGorm.Validate.
+func Validate(model any, filter map[string]any, opts ...ValidateOption) error {
+	return defaultDeepGorm.Validate(model, filter, opts...)
+}
+
+// Validate godoc
+// Checks whether the filter can be used on the model without a database connection, for example in an HTTP
+// handler before a query is made. The same checks as AddDeepFilters are done and the same errors are returned,
+// refer to FilterError. Use WithNamingStrategy if the database doesn't use gorm's default naming strategy.
+func (d *DeepGorm) Validate(model any, filter map[string]any, opts ...ValidateOption) error {
+	config := validateConfig{naming: schema.NamingStrategy{}, dialect: "sqlite"}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	db, err := gorm.Open(validationDialector{name: config.dialect}, &gorm.Config{
+		NamingStrategy: config.naming,
+		DryRun:         true,
+		Logger:         logger.Discard,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to validate filter: %w", err)
+	}
+
+	_, err = d.AddDeepFilters(db, model, filter)
+
+	return err
+}
+
+// validationDialector is a database without a connection, it's only used to build filters in Validate
+type validationDialector struct {
+	name string
+}
+
+func (v validationDialector) Name() string {
+	return v.name
+}
+
+func (v validationDialector) Initialize(*gorm.DB) error {
+	return nil
+}
+
+func (v validationDialector) Migrator(*gorm.DB) gorm.Migrator {
+	return nil
+}
+
+func (v validationDialector) DataTypeOf(field *schema.Field) string {
+	return string(field.DataType)
+}
+
+func (v validationDialector) DefaultValueOf(*schema.Field) clause.Expression {
+	return clause.Expr{SQL: "DEFAULT"}
+}
+
+func (v validationDialector) BindVarTo(writer clause.Writer, _ *gorm.Statement, _ any) {
+	_ = writer.WriteByte('?')
+}
+
+func (v validationDialector) QuoteTo(writer clause.Writer, str string) {
+	_, _ = writer.WriteString(`"` + strings.ReplaceAll(str, `"`, `""`) + `"`)
+}
+
+func (v validationDialector) Explain(sql string, _ ...any) string {
+	return sql
+}
@@ -0,0 +1,147 @@
+package deepgorm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/schema"
+)
+
+func TestValidate_ReturnsNilOnValidFilters(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	tests := map[string]struct {
+		model     any
+		filterMap map[string]any
+		options   []ValidateOption
+	}{
+		"simple": {
+			model:     ComplexStruct1{},
+			filterMap: map[string]any{"value": 5},
+		},
+		"operators": {
+			model:     ComplexStruct1{},
+			filterMap: map[string]any{"value": map[string]any{"$gte": 5}},
+		},
+		"nested relations": {
+			model: ComplexStruct3{},
+			filterMap: map[string]any{
+				"$or": []map[string]any{
+					{"name": "abc"},
+					{"tags": map[string]any{"tag_value": map[string]any{"value": "def"}}},
+				},
+			},
+		},
+		"quantifiers and aggregates": {
+			model: Invoice{},
+			filterMap: map[string]any{
+				"line_items": map[string]any{
+					"$all":   map[string]any{"description": "abc"},
+					"$count": map[string]any{"$gt": 3},
+					"$sum":   map[string]any{"amount": map[string]any{"$gt": 1000}},
+				},
+			},
+		},
+		"embedded struct": {
+			model:     EmbeddedPerson{},
+			filterMap: map[string]any{"address": map[string]any{"city": "Utrecht"}},
+		},
+		"json column": {
+			model:     JSONService{},
+			filterMap: map[string]any{"metadata": map[string]any{"labels": map[string]any{"env": "prod"}}},
+			options:   []ValidateOption{WithDialect("postgres")},
+		},
+		"composite keys": {
+			model:     CompositeOrder{},
+			filterMap: map[string]any{"lines": map[string]any{"product": "pear"}},
+			options:   []ValidateOption{WithDialect("sqlserver")},
+		},
+		"naming strategy": {
+			model:     ComplexStruct1{},
+			filterMap: map[string]any{"nested": map[string]any{"title": "abc"}},
+			options:   []ValidateOption{WithNamingStrategy(schema.NamingStrategy{NameReplacer: strings.NewReplacer("Name", "Title")})},
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Act
+			err := Validate(testData.model, testData.filterMap, testData.options...)
+
+			// Assert
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestValidate_ReturnsErrorOnInvalidFilters(t *testing.T) {
+	t.Parallel()
+	t.Cleanup(cleanupCache)
+	tests := map[string]struct {
+		model        any
+		filterMap    map[string]any
+		options      []ValidateOption
+		expectedErr  error
+		expectedPath string
+	}{
+		"unknown field": {
+			model:        ComplexStruct3{},
+			filterMap:    map[string]any{"tags": map[string]any{"tag_value": map[string]any{"unknown": "abc"}}},
+			expectedErr:  ErrFieldDoesNotExist,
+			expectedPath: "tags.tag_value.unknown",
+		},
+		"invalid operator": {
+			model:        ComplexStruct1{},
+			filterMap:    map[string]any{"value": map[string]any{"$like": "abc"}},
+			expectedErr:  ErrUnsupportedOperator,
+			expectedPath: "value",
+		},
+		"json column on unsupported database": {
+			model:        JSONService{},
+			filterMap:    map[string]any{"metadata": map[string]any{"env": "prod"}},
+			options:      []ValidateOption{WithDialect("sqlserver")},
+			expectedErr:  ErrUnsupportedOperator,
+			expectedPath: "metadata.env",
+		},
+		"naming strategy": {
+			model:        ComplexStruct1{},
+			filterMap:    map[string]any{"nested": map[string]any{"name": "abc"}},
+			options:      []ValidateOption{WithNamingStrategy(schema.NamingStrategy{NameReplacer: strings.NewReplacer("Name", "Title")})},
+			expectedErr:  ErrFieldDoesNotExist,
+			expectedPath: "nested.name",
+		},
+	}
+
+	for name, testData := range tests {
+		testData := testData
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// Act
+			err := Validate(testData.model, testData.filterMap, testData.options...)
+
+			// Assert
+			assert.ErrorIs(t, err, testData.expectedErr)
+
+			var filterError *FilterError
+			if assert.True(t, errors.As(err, &filterError)) {
+				assert.Equal(t, testData.expectedPath, filterError.Path)
+			}
+		})
+	}
+}
+
+func TestDeepGorm_Validate_UsesConfiguration(t *testing.T) {
+	t.Parallel()
+	// Arrange
+	plugin := New(WithAllErrors())
+
+	// Act
+	err := plugin.Validate(ComplexStruct1{}, map[string]any{"a": 1, "b": 2})
+
+	// Assert
+	assert.EqualError(t, err, "failed to add filters for 'complex_struct1.a': field does not exist\nfailed to add filters for 'complex_struct1.b': field does not exist")
+}